@@ -22,6 +22,9 @@ const (
   	(0,0)       (0,c)
 */
 type Canvas struct {
+	// NoColor renders rows without colors and ansi formatting, e.g. for pipes
+	NoColor bool
+
 	colMax     int
 	rowMax     int
 	values     [][]rune
@@ -63,8 +66,17 @@ func (c *Canvas) SetColor(row, col int, color utils.Color) { //uint8
 	//in the future this would be a better alternative:
 	//c.formatting[row][col] = fmt.Sprintf("38;2;%d;%d;%d;1", r, g, b) //+= ?
 	//for now.. we use the basic(only) alternative
-	number := 16 + 36*color.R + 6*color.G + color.B
-	c.SetAnsi(row, col, fmt.Sprintf("38;5;%d", number))
+	c.SetAnsi(row, col, colorCode(color))
+}
+
+// Colorize wraps text in the ansi escape sequence of color.
+func Colorize(text string, color utils.Color) string {
+	return fmt.Sprintf(FormatStr, colorCode(color), text)
+}
+
+func colorCode(color utils.Color) string {
+	number := 16 + 36*int(color.R) + 6*int(color.G) + int(color.B)
+	return fmt.Sprintf("38;5;%d", number)
 }
 
 func (c *Canvas) SetVerticalBar(col int, ru rune) {
@@ -97,6 +109,9 @@ func (c *Canvas) Row(r int) string {
 }
 
 func (c *Canvas) row(r int) string {
+	if c.NoColor {
+		return string(c.values[r])
+	}
 	result := ""
 	for i, ch := range c.values[r] {
 		one := fmt.Sprintf(FormatStr, c.formatting[r][i], string(ch))
@@ -1,59 +1,226 @@
 package config
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
-	"github.com/dbriemann/geopard"
+	"github.com/dbriemann/sunlens/coords"
+	"github.com/dbriemann/sunlens/forecastio"
+	"github.com/dbriemann/sunlens/geocode"
+	"github.com/dbriemann/sunlens/locate"
 	"github.com/dbriemann/sunlens/utils"
 )
 
-// global settings
-var (
-	Settings *Config
-)
-
 type Location struct {
 	City      string
 	Latitude  float64
 	Longitude float64
 	Shortcut  string
+	Aliases   []string `json:",omitempty"` // alternative names the location is found by
+
+	// optional settings overriding the global ones for this location
+	UnitFormat string            `json:",omitempty"`
+	Language   string            `json:",omitempty"`
+	HeatMap    []utils.HeatColor `json:",omitempty"`
+	Provider   string            `json:",omitempty"`
+	Timezone   string            `json:",omitempty"` // IANA name like "Europe/Oslo", local time if empty
 }
 
+// Chooser picks one of several distinct geocoding results for the query desc
+// and returns its index.
+type Chooser func(desc string, results []geocode.Result) (int, error)
+
+// FirstResult is a Chooser that always takes the first(best) result.
+func FirstResult(desc string, results []geocode.Result) (int, error) {
+	return 0, nil
+}
+
+// RejectAmbiguous is a Chooser that fails with the list of candidates.
+func RejectAmbiguous(desc string, results []geocode.Result) (int, error) {
+	return 0, &AmbiguousError{Query: desc, Results: results}
+}
+
+// AmbiguousError is returned if a query matches several distinct places
+// and none of them was chosen.
+type AmbiguousError struct {
+	Query   string
+	Results []geocode.Result
+}
+
+func (e *AmbiguousError) Error() string {
+	msg := "Multiple places match: " + e.Query
+	for i, r := range e.Results {
+		msg += fmt.Sprintf("\n %2d) %s", i+1, r)
+	}
+	return msg + "\nPlease be more specific or pass --first to use the best match."
+}
+
+// distinctRadius is the distance in kilometers within which geocoding results
+// are considered the same place, e.g. a city and its center district.
+const distinctRadius = 10.0
+
 // NewLocation creates a location from its desc description
-// by querying the google api for lat, long and exact name
-func NewLocation(desc string) (Location, error) {
+// by querying the geocoder g for lat, long and exact name.
+// If several distinct places match, choose picks one. A nil
+// choose takes the first(best) result.
+func NewLocation(g geocode.Geocoder, desc string, choose Chooser) (Location, error) {
 	loc := Location{Shortcut: "#" + desc}
 
 	// find geo coordinates
-	instance := geopard.GetInstance()
-
-	r, err := instance.Geocode(desc)
+	results, err := g.Geocode(desc)
 	if err != nil {
 		return loc, errors.New("Unable to get latitude and longitude for: " + desc + "\nError: " + err.Error())
 	}
-	if len(r.Results) > 0 {
-		// Just use first(best) result.
-		loc.City = r.Results[0].FormattedAddr
-		loc.Latitude = r.Results[0].Geometry.Location.Lat
-		loc.Longitude = r.Results[0].Geometry.Location.Lng
-	}
 
-	fmt.Println(loc)
+	results = distinct(results)
+	i := 0
+	if len(results) > 1 && choose != nil {
+		if i, err = choose(desc, results); err != nil {
+			return loc, err
+		}
+		if i < 0 || i >= len(results) {
+			return loc, errors.New("No place chosen for: " + desc)
+		}
+	}
+	loc.City = results[i].Name
+	loc.Latitude = results[i].Latitude
+	loc.Longitude = results[i].Longitude
 
 	return loc, nil
 }
 
+// distinct drops results that are near a better result.
+func distinct(results []geocode.Result) []geocode.Result {
+	out := make([]geocode.Result, 0, len(results))
+	for _, r := range results {
+		dup := false
+		for _, o := range out {
+			if utils.Distance(o.Latitude, o.Longitude, r.Latitude, r.Longitude) < distinctRadius {
+				dup = true
+				break
+			}
+		}
+		if !dup {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// NearbyDistance is the distance in kilometers below which two locations are considered the same place.
+const NearbyDistance = 1.0
+
+// FindLocation returns the index of the location with the given shortcut or -1.
+func (c *Config) FindLocation(shortcut string) int {
+	for i, l := range c.Locations {
+		if l.Shortcut == shortcut {
+			return i
+		}
+	}
+	return -1
+}
+
+// Shortcut returns the shortcut for name as it is stored in the config,
+// which means prefixed with "#".
+func Shortcut(name string) string {
+	if strings.HasPrefix(name, "#") {
+		return name
+	}
+	return "#" + name
+}
+
+// RemoveLocation deletes the location with the given shortcut from the
+// locations, groups and profiles. The default location is kept pointing
+// at the same place if possible.
+func (c *Config) RemoveLocation(shortcut string) error {
+	i := c.FindLocation(shortcut)
+	if i < 0 {
+		return errors.New("Unknown location: " + shortcut)
+	}
+	if len(c.Locations) == 1 {
+		return errors.New("Cannot remove the last location: " + shortcut)
+	}
+	if err := c.removeProfileRefs(shortcut, c.groupsOnlyOf(shortcut)); err != nil {
+		return err
+	}
+	c.Locations = append(c.Locations[:i], c.Locations[i+1:]...)
+	c.removeMember(shortcut)
+	if c.DefaultLocation > i || c.DefaultLocation >= len(c.Locations) {
+		c.DefaultLocation--
+	}
+	if c.DefaultLocation < 0 {
+		c.DefaultLocation = 0
+	}
+	return nil
+}
+
+// RenameLocation changes the shortcut of a saved location and
+// all references to it in groups and profiles.
+func (c *Config) RenameLocation(shortcut, newShortcut string) error {
+	i := c.FindLocation(shortcut)
+	if i < 0 {
+		return errors.New("Unknown location: " + shortcut)
+	}
+	if c.FindLocation(newShortcut) >= 0 {
+		return errors.New("Location already exists: " + newShortcut)
+	}
+	c.Locations[i].Shortcut = newShortcut
+	c.renameRefs(shortcut, newShortcut)
+	return nil
+}
+
+// renameRefs replaces a location shortcut in all groups and profiles.
+func (c *Config) renameRefs(shortcut, newShortcut string) {
+	c.renameMember(shortcut, newShortcut)
+	c.renameProfileRefs(shortcut, newShortcut)
+}
+
+// SetDefaultLocation makes the location with the given shortcut the default one.
+func (c *Config) SetDefaultLocation(shortcut string) error {
+	i := c.FindLocation(shortcut)
+	if i < 0 {
+		return errors.New("Unknown location: " + shortcut)
+	}
+	c.DefaultLocation = i
+	return nil
+}
+
+// UpsertLocation adds loc to the saved locations unless a location with the same
+// shortcut or one nearby is already stored. It returns the stored location and
+// whether the config was changed.
+func (c *Config) UpsertLocation(loc Location) (Location, bool) {
+	if i := c.FindLocation(loc.Shortcut); i >= 0 {
+		return c.Locations[i], false
+	}
+	for _, l := range c.Locations {
+		if utils.Distance(l.Latitude, l.Longitude, loc.Latitude, loc.Longitude) < NearbyDistance {
+			return l, false
+		}
+	}
+	c.Locations = append(c.Locations, loc)
+	return loc, true
+}
+
 // Config stores all basic settings the user should adjust.
 type Config struct {
-	ApiKey string
-	//	City             string
-	//	Latitude         float64
-	//	Longitude        float64
-	UnitFormat string // us(farenheit, miles..), si(celsius, meters..), ca, uk, auto(location dependent)
+	Version int // schema version of the file, see SchemaVersion
+	ApiKey  string
+	// ApiKeyFile and ApiKeyCommand keep the api key out of this file, see resolveApiKey.
+	ApiKeyFile    string `json:",omitempty"`
+	ApiKeyCommand string `json:",omitempty"`
+	BaseURL       string // forecast api base url, e.g. a team proxy.. empty uses api.darksky.net
+	// Provider selects the forecast api by name: darksky, pirateweather
+	// or one of Providers. If empty BaseURL is used.
+	Provider   string              `json:",omitempty"`
+	Providers  map[string]Provider `json:",omitempty"`
+	UnitFormat string              // us(farenheit, miles..), si(celsius, meters..), ca, uk, auto(location dependent)
 	HeatMap    []utils.HeatColor
 	/*
 		language may be one of the following:
@@ -62,57 +229,276 @@ type Config struct {
 		pt (Portuguese), ru (Russian), tet (Tetum), or x-pig-latin (Igpay Atinlay)
 	*/
 	Language        string     // language code.. see above
+	Geocoder        string     // geocoding service: nominatim (default), photon, google or geonames (offline)
+	GeocoderKey     string     `json:",omitempty"` // api key for the geocoding service, only needed by google
+	GeocoderURL     string     `json:",omitempty"` // endpoint of a self hosted geocoding service
+	GazetteerPath   string     `json:",omitempty"` // GeoNames cities file used by the geonames geocoder
 	DefaultLocation int        // sets the number of the default location in "Location" slice
 	Locations       []Location // saves all queried locations
+
+	// Panels lists the parts of the forecast that are rendered, in this order.
+	Panels []string `json:",omitempty"`
+	// Glyphs selects the symbols of the weather panel: auto (default), unicode, emoji or ascii.
+	// Auto uses unicode if the locale is UTF-8 and ascii otherwise.
+	Glyphs string `json:",omitempty"`
+
+	// Profiles bundle settings that replace the global ones when selected
+	// with --profile or the SUNLENS_PROFILE environment variable.
+	Profiles map[string]Profile `json:",omitempty"`
+	// Groups names lists of location shortcuts, used as "@name" wherever a location is expected
+	Groups map[string][]string `json:",omitempty"`
+
+	// Here configures how the "@here" location detects the current position.
+	Here HereSettings
+
+	// WordAliases maps what3words style aliases to coordinates. A location
+	// argument "///table.chair.lamp" looks up the key "table.chair.lamp".
+	WordAliases map[string]coords.Point `json:",omitempty"`
+
+	fileApiKey   string // api key as stored in the file
+	apiKeySource string // where ApiKey came from
+	fileVersion  int    // schema version of the file before migration
 }
 
-// LoadConfig creates a new Config object from a json file.
-func LoadConfig(path string, loc Location) (*Config, error) {
-	c := &Config{}
-	b, err := ioutil.ReadFile(path)
-	if err == nil {
-		err = json.Unmarshal(b, &c)
-		if err == nil {
-			if c.ApiKey == "" {
-				return nil, errors.New("Please set your ApiKey in config file.")
+// names of the renderable panels
+const (
+	PanelDays        = "days"          // date header
+	PanelWeather     = "weather"       // weather symbols
+	PanelWind        = "wind"          // wind direction and speed
+	PanelTemperature = "temperature"   // temperature curve
+	PanelHours       = "hours"         // hour axis
+	PanelPrecip      = "precipitation" // precipitation bars
+)
+
+// Panels lists all known panels.
+var Panels = []string{PanelDays, PanelWeather, PanelWind, PanelTemperature, PanelHours, PanelPrecip}
+
+// DefaultPanels are rendered if no panels are configured.
+var DefaultPanels = []string{PanelDays, PanelWeather, PanelWind, PanelTemperature, PanelHours, PanelPrecip}
+
+// glyph sets of the weather panel
+const (
+	GlyphsAuto    = "auto"
+	GlyphsUnicode = "unicode"
+	GlyphsEmoji   = "emoji"
+	GlyphsASCII   = "ascii"
+)
+
+// GlyphSets lists all glyph sets.
+var GlyphSets = []string{GlyphsAuto, GlyphsUnicode, GlyphsEmoji, GlyphsASCII}
+
+// Provider is a Dark Sky compatible forecast api.
+type Provider struct {
+	BaseURL string
+	ApiKey  string `json:",omitempty"` // Config.ApiKey is used if empty
+}
+
+// LocationSettings are the effective settings for rendering a single location.
+type LocationSettings struct {
+	BaseURL    string
+	ApiKey     string
+	UnitFormat string
+	Language   string
+	HeatMap    []utils.HeatColor
+	Panels     []string
+	Glyphs     string
+	Timezone   *time.Location
+}
+
+// SettingsFor resolves the settings for loc. Settings of the location
+// override the global ones.
+func (c *Config) SettingsFor(loc Location) (LocationSettings, error) {
+	s := LocationSettings{
+		BaseURL:    c.BaseURL,
+		ApiKey:     c.ApiKey,
+		UnitFormat: c.UnitFormat,
+		Language:   c.Language,
+		HeatMap:    c.HeatMap,
+		Panels:     c.Panels,
+		Glyphs:     c.Glyphs,
+		Timezone:   time.Local,
+	}
+	if len(s.Panels) == 0 {
+		s.Panels = DefaultPanels
+	}
+
+	provider := c.Provider
+	if loc.Provider != "" {
+		provider = loc.Provider
+	}
+	if provider != "" {
+		if p, ok := c.Providers[provider]; ok {
+			s.BaseURL = p.BaseURL
+			if p.ApiKey != "" {
+				s.ApiKey = p.ApiKey
 			}
+		} else if u, ok := forecastio.Providers[provider]; ok {
+			s.BaseURL = u
+		} else {
+			return s, errors.New("Unknown provider: " + provider)
 		}
-		return c, err
-	}
-	if _, ok := err.(*os.PathError); ok {
-		// default fallback values if no config is found
-		c.ApiKey = ""
-		c.UnitFormat = "auto"
-		c.Language = "en"
-		c.HeatMap = []utils.HeatColor{
-			utils.HeatColor{Temperature: -10, Color: utils.Color{R: 0, G: 0, B: 5}}, //blue
-			utils.HeatColor{Temperature: 0, Color: utils.Color{R: 0, G: 5, B: 5}},   //cyan
-			utils.HeatColor{Temperature: 10, Color: utils.Color{R: 0, G: 5, B: 0}},  //green
-			utils.HeatColor{Temperature: 20, Color: utils.Color{R: 5, G: 5, B: 0}},  //yellow
-			utils.HeatColor{Temperature: 30, Color: utils.Color{R: 5, G: 0, B: 0}},  //red
-		}
+	}
 
-		c.Locations = make([]Location, 1)
-		if c.Locations[0], err = NewLocation("Darmstadt"); err != nil {
-			return nil, errors.New("Could not create default location: " + err.Error())
+	if loc.UnitFormat != "" {
+		s.UnitFormat = loc.UnitFormat
+	}
+	if loc.Language != "" {
+		s.Language = loc.Language
+	}
+	if len(loc.HeatMap) > 0 {
+		s.HeatMap = loc.HeatMap
+	}
+	if loc.Timezone != "" {
+		tz, err := time.LoadLocation(loc.Timezone)
+		if err != nil {
+			return s, errors.New("Unknown timezone for " + loc.Shortcut + ": " + err.Error())
 		}
+		s.Timezone = tz
+	}
+	return s, nil
+}
 
-		if err := c.Save(path); err != nil {
-			return nil, errors.New("Could not save default config: " + err.Error())
-		} else {
-			return nil, errors.New(fmt.Sprintf("Created default config file: %s\n%s", path, "Please edit it and change the parameters."))
-		}
+// HereSettings configures the position sources of the "@here" location.
+// Sources are tried in order: gpsd, ip geolocation, default location.
+type HereSettings struct {
+	GPSD      string // gpsd address, localhost:2947 if empty, "off" to disable
+	IPGeoURL  string // ip geolocation endpoint, https://ipapi.co/json/ if empty, "off" to disable
+	CacheTime int    // seconds a detected position is reused
+}
+
+// Locator creates the position detector for the "@here" location.
+// Positions are cached in cacheFile.
+func (h HereSettings) Locator(cacheFile string) *locate.Locator {
+	return &locate.Locator{
+		GPSD:      h.GPSD,
+		IPGeoURL:  h.IPGeoURL,
+		CacheFile: cacheFile,
+		CacheTime: time.Duration(h.CacheTime) * time.Second,
+	}
+}
+
+// ErrNoConfig is returned by LoadConfig if there is no config file yet.
+var ErrNoConfig = errors.New("No config file found. Run \"sunlens config init\" to create one.")
+
+// LoadConfig creates a new Config object from a json file.
+// Older config files are migrated to the current schema version.
+// SUNLENS_* environment variables override the settings of the file.
+func LoadConfig(path string, loc Location) (*Config, error) {
+	c, err := readConfig(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNoConfig
 	} else if err != nil {
-		return nil, errors.New("Error in config file: " + path + " : " + err.Error())
+		return nil, err
+	}
+	if c.needsMigration() {
+		//the migration is written under the lock, fn has nothing else to change
+		if err := Update(path, func(*Config) error { return nil }); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyEnv(); err != nil {
+		return nil, err
 	}
-	return c, err
+	if err := c.resolveApiKey(); err != nil {
+		return nil, err
+	}
+	if problems := c.Validate(); len(problems) > 0 {
+		return nil, &ValidationError{File: path, Problems: problems}
+	}
+	return c, nil
+}
+
+// NewGeocoder creates the geocoding backend selected in c.
+func (c *Config) NewGeocoder() (geocode.Geocoder, error) {
+	return geocode.New(c.Geocoder, geocode.Options{
+		ApiKey:   c.GeocoderKey,
+		BaseURL:  c.GeocoderURL,
+		Language: c.Language,
+		Path:     c.GazetteerPath,
+	})
 }
 
 // Save saves the Config object c to a json file.
+// The file is replaced atomically so readers never see a partial config.
+// An api key from an external source is never written to the file.
 func (c *Config) Save(path string) error {
-	j, err := json.MarshalIndent(c, "", "\t")
-	if err == nil {
-		return ioutil.WriteFile(path, j, 0600)
+	out := *c
+	if c.ApiKeySource() != KeySourceConfig {
+		out.ApiKey = c.fileApiKey
+	}
+	j, err := json.MarshalIndent(&out, "", "\t")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, j, 0600)
+}
+
+// Update locks the config file at path, reads its current content, applies
+// fn and writes the result back. Parallel runs are serialized by the lock, so
+// changes made by another process in between are never lost. The file is only
+// rewritten if fn changed something or the file needs to be migrated.
+func Update(path string, fn func(c *Config) error) error {
+	unlock, err := lockFile(path + ".lock")
+	if err != nil {
+		return errors.New("Could not lock config file: " + err.Error())
+	}
+	defer unlock()
+
+	c, err := readConfig(path)
+	if os.IsNotExist(err) {
+		return errors.New("Error in config file: " + path + " : " + err.Error())
+	} else if err != nil {
+		return err
+	}
+	before, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := fn(c); err != nil {
+		return err
+	}
+
+	after, err := json.MarshalIndent(c, "", "\t")
+	if err != nil {
+		return err
+	}
+	if c.needsMigration() {
+		return c.migrateFile(path)
+	}
+	if bytes.Equal(before, after) {
+		return nil
+	}
+	return writeFileAtomic(path, after, 0600)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, name := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+	tmp, err := ioutil.TempFile(dir, "."+name+".*")
+	if err != nil {
+		return err
+	}
+	// remove is a no-op after a successful rename
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
 	}
-	return err
+	return os.Rename(tmp.Name(), path)
 }
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// EnvPrefix starts the names of environment variables overriding config settings.
+const EnvPrefix = "SUNLENS_"
+
+// EnvName returns the environment variable overriding the config field
+// name, e.g. SUNLENS_UNIT_FORMAT for UnitFormat or SUNLENS_HERE_GPSD for Here.GPSD.
+func EnvName(name ...string) string {
+	parts := make([]string, len(name))
+	for i, n := range name {
+		parts[i] = snakeUpper(n)
+	}
+	return EnvPrefix + strings.Join(parts, "_")
+}
+
+// snakeUpper turns UnitFormat into UNIT_FORMAT and IPGeoURL into IP_GEO_URL.
+func snakeUpper(s string) string {
+	r := []rune(s)
+	out := make([]rune, 0, len(r)+4)
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) &&
+			(unicode.IsLower(r[i-1]) || (i+1 < len(r) && unicode.IsLower(r[i+1]))) {
+			out = append(out, '_')
+		}
+		out = append(out, unicode.ToUpper(c))
+	}
+	return string(out)
+}
+
+// applyEnv overrides the fields of c with SUNLENS_* environment variables.
+// Strings, numbers and booleans are given as is, lists and maps as json.
+// Fields of nested structs like Here have their own variables. The api key
+// is left to resolveApiKey, so it is never saved by accident.
+func (c *Config) applyEnv() error {
+	return applyEnvFields(reflect.ValueOf(c).Elem(), nil)
+}
+
+func applyEnvFields(v reflect.Value, parents []string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || (len(parents) == 0 && (f.Name == "Version" || f.Name == "ApiKey")) {
+			continue
+		}
+		names := append(append([]string{}, parents...), f.Name)
+		if f.Type.Kind() == reflect.Struct {
+			if err := applyEnvFields(v.Field(i), names); err != nil {
+				return err
+			}
+			continue
+		}
+
+		env := EnvName(names...)
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		if err := setField(v.Field(i), value); err != nil {
+			return errors.New("Invalid value of " + env + ": " + err.Error())
+		}
+	}
+	return nil
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		ptr := reflect.New(field.Type())
+		if err := json.Unmarshal([]byte(value), ptr.Interface()); err != nil {
+			return err
+		}
+		field.Set(ptr.Elem())
+	}
+	return nil
+}
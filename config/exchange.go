@@ -0,0 +1,431 @@
+package config
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// location exchange formats
+const (
+	FormatGeoJSON = "geojson"
+	FormatGPX     = "gpx"
+	FormatCSV     = "csv"
+)
+
+// Formats lists all location exchange formats.
+var Formats = []string{FormatGeoJSON, FormatGPX, FormatCSV}
+
+// FormatOf guesses the exchange format from the extension of path.
+func FormatOf(path string) (string, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".geojson", ".json":
+		return FormatGeoJSON, nil
+	case ".gpx":
+		return FormatGPX, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+	return "", errors.New("Unknown file format of " + path + ", use one of: " + strings.Join(Formats, ", "))
+}
+
+// ReadLocations reads locations in the given exchange format. Locations without
+// a shortcut get one made from their name.
+func ReadLocations(r io.Reader, format string) ([]Location, error) {
+	var locs []Location
+	var err error
+	switch format {
+	case FormatGeoJSON:
+		locs, err = readGeoJSON(r)
+	case FormatGPX:
+		locs, err = readGPX(r)
+	case FormatCSV:
+		locs, err = readCSV(r)
+	default:
+		return nil, errors.New("Unknown format: " + format + ", use one of: " + strings.Join(Formats, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range locs {
+		l := &locs[i]
+		if l.Latitude < -90 || l.Latitude > 90 || l.Longitude < -180 || l.Longitude > 180 {
+			return nil, fmt.Errorf("Invalid coordinates of %s: %.5f, %.5f", l.City, l.Latitude, l.Longitude)
+		}
+		if l.Shortcut == "" {
+			l.Shortcut = shortcutOf(l.City)
+		}
+		if l.Shortcut == "#" {
+			return nil, fmt.Errorf("Location %d has neither name nor shortcut", i+1)
+		}
+		l.Shortcut = Shortcut(l.Shortcut)
+	}
+	return locs, nil
+}
+
+// WriteLocations writes locs in the given exchange format.
+func WriteLocations(w io.Writer, format string, locs []Location) error {
+	switch format {
+	case FormatGeoJSON:
+		return writeGeoJSON(w, locs)
+	case FormatGPX:
+		return writeGPX(w, locs)
+	case FormatCSV:
+		return writeCSV(w, locs)
+	}
+	return errors.New("Unknown format: " + format + ", use one of: " + strings.Join(Formats, ", "))
+}
+
+// shortcutOf turns a name like "Field Site 12" into "#field-site-12".
+func shortcutOf(name string) string {
+	return "#" + strings.ToLower(strings.Join(strings.Fields(name), "-"))
+}
+
+// GeoJSON
+
+type geoFeatureCollection struct {
+	Type     string       `json:"type"`
+	Features []geoFeature `json:"features"`
+}
+
+type geoFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoGeometry           `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoGeometry struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+func readGeoJSON(r io.Reader) ([]Location, error) {
+	fc := geoFeatureCollection{}
+	if err := json.NewDecoder(r).Decode(&fc); err != nil {
+		return nil, errors.New("Invalid GeoJSON: " + err.Error())
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, errors.New("Invalid GeoJSON: expected a FeatureCollection, got " + fc.Type)
+	}
+
+	locs := make([]Location, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		if f.Geometry == nil || f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("Invalid GeoJSON: feature %d is not a point", i+1)
+		}
+		locs = append(locs, Location{
+			City:      stringProperty(f.Properties, "name", "title"),
+			Shortcut:  stringProperty(f.Properties, "shortcut"),
+			Latitude:  f.Geometry.Coordinates[1], // GeoJSON positions are lng, lat
+			Longitude: f.Geometry.Coordinates[0],
+		})
+	}
+	return locs, nil
+}
+
+func stringProperty(props map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if s, ok := props[k].(string); ok && s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func writeGeoJSON(w io.Writer, locs []Location) error {
+	fc := geoFeatureCollection{Type: "FeatureCollection", Features: []geoFeature{}}
+	for _, l := range locs {
+		fc.Features = append(fc.Features, geoFeature{
+			Type:       "Feature",
+			Geometry:   &geoGeometry{Type: "Point", Coordinates: []float64{l.Longitude, l.Latitude}},
+			Properties: map[string]interface{}{"name": l.City, "shortcut": l.Shortcut},
+		})
+	}
+	j, err := json.MarshalIndent(fc, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(j, '\n'))
+	return err
+}
+
+// GPX, the shortcut is kept in the comment of a waypoint
+
+type gpxFile struct {
+	XMLName   xml.Name      `xml:"gpx"`
+	Version   string        `xml:"version,attr"`
+	Creator   string        `xml:"creator,attr"`
+	Xmlns     string        `xml:"xmlns,attr,omitempty"`
+	Waypoints []gpxWaypoint `xml:"wpt"`
+}
+
+type gpxWaypoint struct {
+	Latitude  float64 `xml:"lat,attr"`
+	Longitude float64 `xml:"lon,attr"`
+	Name      string  `xml:"name,omitempty"`
+	Comment   string  `xml:"cmt,omitempty"`
+}
+
+func readGPX(r io.Reader) ([]Location, error) {
+	g := gpxFile{}
+	if err := xml.NewDecoder(r).Decode(&g); err != nil {
+		return nil, errors.New("Invalid GPX: " + err.Error())
+	}
+
+	locs := make([]Location, 0, len(g.Waypoints))
+	for _, wpt := range g.Waypoints {
+		loc := Location{City: strings.TrimSpace(wpt.Name), Latitude: wpt.Latitude, Longitude: wpt.Longitude}
+		if cmt := strings.TrimSpace(wpt.Comment); strings.HasPrefix(cmt, "#") && !strings.ContainsAny(cmt, " \t\n") {
+			loc.Shortcut = cmt
+		}
+		locs = append(locs, loc)
+	}
+	return locs, nil
+}
+
+func writeGPX(w io.Writer, locs []Location) error {
+	g := gpxFile{Version: "1.1", Creator: "sunlens", Xmlns: "http://www.topografix.com/GPX/1/1"}
+	for _, l := range locs {
+		g.Waypoints = append(g.Waypoints, gpxWaypoint{Latitude: l.Latitude, Longitude: l.Longitude, Name: l.City, Comment: l.Shortcut})
+	}
+	x, err := xml.MarshalIndent(g, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, xml.Header+string(x)+"\n")
+	return err
+}
+
+// CSV with the columns name, lat, lon, shortcut. A header row may give
+// another order, the shortcut column is optional.
+
+var csvColumns = map[string]string{
+	"name": "name", "city": "name",
+	"lat": "lat", "latitude": "lat",
+	"lon": "lon", "lng": "lon", "long": "lon", "longitude": "lon",
+	"shortcut": "shortcut",
+}
+
+func readCSV(r io.Reader) ([]Location, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	rows, err := cr.ReadAll()
+	if err != nil {
+		return nil, errors.New("Invalid CSV: " + err.Error())
+	}
+
+	cols := map[string]int{"name": 0, "lat": 1, "lon": 2, "shortcut": 3}
+	if len(rows) > 0 && isCSVHeader(rows[0]) {
+		cols = map[string]int{}
+		for i, h := range rows[0] {
+			if c, ok := csvColumns[strings.ToLower(strings.TrimSpace(h))]; ok {
+				cols[c] = i
+			}
+		}
+		if _, ok := cols["lat"]; !ok {
+			return nil, errors.New("Invalid CSV: no lat column in header")
+		}
+		if _, ok := cols["lon"]; !ok {
+			return nil, errors.New("Invalid CSV: no lon column in header")
+		}
+		rows = rows[1:]
+	}
+
+	field := func(row []string, col string) string {
+		if i, ok := cols[col]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+
+	var locs []Location
+	for n, row := range rows {
+		if len(row) == 1 && strings.TrimSpace(row[0]) == "" {
+			continue
+		}
+		lat, err := strconv.ParseFloat(field(row, "lat"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: latitude in row %d: %s", n+1, field(row, "lat"))
+		}
+		lng, err := strconv.ParseFloat(field(row, "lon"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid CSV: longitude in row %d: %s", n+1, field(row, "lon"))
+		}
+		locs = append(locs, Location{City: field(row, "name"), Latitude: lat, Longitude: lng, Shortcut: field(row, "shortcut")})
+	}
+	return locs, nil
+}
+
+// isCSVHeader reports whether row names columns instead of holding coordinates.
+func isCSVHeader(row []string) bool {
+	for _, f := range row {
+		if _, ok := csvColumns[strings.ToLower(strings.TrimSpace(f))]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func writeCSV(w io.Writer, locs []Location) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"name", "lat", "lon", "shortcut"})
+	for _, l := range locs {
+		cw.Write([]string{
+			l.City,
+			strconv.FormatFloat(l.Latitude, 'f', -1, 64),
+			strconv.FormatFloat(l.Longitude, 'f', -1, 64),
+			l.Shortcut,
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// conflict policies of ImportLocations
+const (
+	ConflictAbort   = "abort"   // change nothing if there is any conflict
+	ConflictSkip    = "skip"    // keep the saved location
+	ConflictReplace = "replace" // overwrite the saved location
+)
+
+// Conflict is an imported location that clashes with a saved one or an
+// earlier one of the same import.
+type Conflict struct {
+	Imported Location
+	Existing Location
+	Reason   string // "shortcut" or "nearby"
+}
+
+func (c Conflict) String() string {
+	if c.Reason == "shortcut" {
+		return fmt.Sprintf("%s (%s): shortcut is already used by %s", c.Imported.Shortcut, c.Imported.City, c.Existing.City)
+	}
+	return fmt.Sprintf("%s (%s): %.2f km from %s (%s)", c.Imported.Shortcut, c.Imported.City,
+		utils.Distance(c.Imported.Latitude, c.Imported.Longitude, c.Existing.Latitude, c.Existing.Longitude),
+		c.Existing.Shortcut, c.Existing.City)
+}
+
+// ImportResult lists what ImportLocations did.
+type ImportResult struct {
+	Added     []Location
+	Replaced  []Location
+	Unchanged []Location // already saved with the same shortcut nearby
+	Conflicts []Conflict
+}
+
+// ConflictError is returned by ImportLocations with the ConflictAbort policy.
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	msg := fmt.Sprintf("%d location(s) conflict with saved ones, nothing was imported:", len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		msg += "\n  " + c.String()
+	}
+	return msg
+}
+
+// ImportLocations adds locs to the saved locations. An imported location
+// conflicts if its shortcut is taken or it is within NearbyDistance of a saved
+// location. Locations that are already saved are left alone and duplicates
+// within locs are always skipped. Groups and profiles follow the shortcut
+// of a replaced location.
+func (c *Config) ImportLocations(locs []Location, policy string) (ImportResult, error) {
+	res := ImportResult{}
+	if policy != ConflictAbort && policy != ConflictSkip && policy != ConflictReplace {
+		return res, errors.New("Unknown conflict policy: " + policy + ", use abort, skip or replace")
+	}
+
+	// drop duplicates within the import first
+	var batch []Location
+	var dupConflicts []Conflict
+	for _, loc := range locs {
+		if i, reason := findConflict(batch, loc); i >= 0 {
+			dupConflicts = append(dupConflicts, Conflict{Imported: loc, Existing: batch[i], Reason: reason})
+			continue
+		}
+		batch = append(batch, loc)
+	}
+
+	var conflicts []Conflict
+	var targets []int // index of the clashing saved location of every conflict
+	var fresh []Location
+	for _, loc := range batch {
+		if i, reason := findConflict(c.Locations, loc); i >= 0 {
+			l := c.Locations[i]
+			if reason == "shortcut" && utils.Distance(l.Latitude, l.Longitude, loc.Latitude, loc.Longitude) < NearbyDistance {
+				res.Unchanged = append(res.Unchanged, l)
+				continue
+			}
+			conflicts = append(conflicts, Conflict{Imported: loc, Existing: c.Locations[i], Reason: reason})
+			targets = append(targets, i)
+			continue
+		}
+		fresh = append(fresh, loc)
+	}
+	if len(conflicts) > 0 && policy == ConflictAbort {
+		return res, &ConflictError{Conflicts: conflicts}
+	}
+
+	replaced := map[int]bool{}
+	for i, cf := range conflicts {
+		t := targets[i]
+		if policy == ConflictSkip || replaced[t] {
+			res.Conflicts = append(res.Conflicts, cf)
+			continue
+		}
+		// a nearby location must not take the shortcut of another saved one
+		if j := findShortcut(c.Locations, cf.Imported.Shortcut); j >= 0 && j != t {
+			res.Conflicts = append(res.Conflicts, cf)
+			continue
+		}
+		l := &c.Locations[t]
+		if l.Shortcut != cf.Imported.Shortcut {
+			c.renameRefs(l.Shortcut, cf.Imported.Shortcut)
+		}
+		l.City, l.Shortcut = cf.Imported.City, cf.Imported.Shortcut
+		l.Latitude, l.Longitude = cf.Imported.Latitude, cf.Imported.Longitude
+		replaced[t] = true
+		res.Replaced = append(res.Replaced, *l)
+	}
+
+	c.Locations = append(c.Locations, fresh...)
+	res.Added = fresh
+	res.Conflicts = append(res.Conflicts, dupConflicts...)
+	return res, nil
+}
+
+// findConflict returns the index of the location in locs that has the
+// shortcut of loc or is near it, and the reason.
+func findConflict(locs []Location, loc Location) (int, string) {
+	if i := findShortcut(locs, loc.Shortcut); i >= 0 {
+		return i, "shortcut"
+	}
+	for i, l := range locs {
+		if utils.Distance(l.Latitude, l.Longitude, loc.Latitude, loc.Longitude) < NearbyDistance {
+			return i, "nearby"
+		}
+	}
+	return -1, ""
+}
+
+// findShortcut returns the index of the location in locs with shortcut, ignoring case.
+func findShortcut(locs []Location, shortcut string) int {
+	for i, l := range locs {
+		if strings.EqualFold(l.Shortcut, shortcut) {
+			return i
+		}
+	}
+	return -1
+}
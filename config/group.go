@@ -0,0 +1,182 @@
+package config
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// GroupPrefix marks a group argument like "@sites". The name "here" is
+// reserved for the current position, see HereLocation.
+const GroupPrefix = "@"
+
+// IsGroup reports whether arg names a group of locations.
+func IsGroup(arg string) bool {
+	arg = strings.TrimSpace(arg)
+	return strings.HasPrefix(arg, GroupPrefix) && len(arg) > len(GroupPrefix) && !strings.EqualFold(arg, HereLocation)
+}
+
+// groupName returns name without the group prefix.
+func groupName(name string) string {
+	return strings.TrimPrefix(strings.TrimSpace(name), GroupPrefix)
+}
+
+// Group returns the saved locations of the named group, which may be given
+// with or without the leading "@". Members that are not saved, e.g. because
+// a profile hides them, are left out.
+func (c *Config) Group(name string) ([]Location, error) {
+	members, ok := c.Groups[groupName(name)]
+	if !ok {
+		for k, m := range c.Groups {
+			if strings.EqualFold(k, groupName(name)) {
+				members, ok = m, true
+				break
+			}
+		}
+	}
+	if !ok {
+		return nil, errors.New("Unknown group: " + GroupPrefix + groupName(name))
+	}
+
+	locs := make([]Location, 0, len(members))
+	for _, m := range members {
+		if i := c.FindLocation(Shortcut(m)); i >= 0 {
+			locs = append(locs, c.Locations[i])
+		}
+	}
+	if len(locs) == 0 {
+		return nil, errors.New("Group has no locations: " + GroupPrefix + groupName(name))
+	}
+	return locs, nil
+}
+
+// GroupNames returns the names of all groups, sorted.
+func (c *Config) GroupNames() []string {
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetGroup replaces the members of the named group. No members delete the group.
+func (c *Config) SetGroup(name string, shortcuts []string) error {
+	name = groupName(name)
+	if err := validGroupName(name); err != nil {
+		return err
+	}
+	if len(shortcuts) == 0 {
+		if _, ok := c.Groups[name]; !ok {
+			return errors.New("Unknown group: " + GroupPrefix + name)
+		}
+		if err := c.removeProfileRefs("", []string{name}); err != nil {
+			return err
+		}
+		delete(c.Groups, name)
+		return nil
+	}
+
+	members := make([]string, 0, len(shortcuts))
+	for _, s := range shortcuts {
+		s = Shortcut(s)
+		if c.FindLocation(s) < 0 {
+			return errors.New("Unknown location: " + s)
+		}
+		if !contains(members, s) {
+			members = append(members, s)
+		}
+	}
+	if c.Groups == nil {
+		c.Groups = make(map[string][]string)
+	}
+	c.Groups[name] = members
+	return nil
+}
+
+// Expand returns the saved locations for names, which are shortcuts and
+// groups. Locations are only returned once.
+func (c *Config) Expand(names []string) ([]Location, error) {
+	var locs []Location
+	seen := map[string]bool{}
+	add := func(l Location) {
+		if !seen[l.Shortcut] {
+			seen[l.Shortcut] = true
+			locs = append(locs, l)
+		}
+	}
+	for _, n := range names {
+		if IsGroup(n) {
+			members, err := c.Group(n)
+			if err != nil {
+				return nil, err
+			}
+			for _, l := range members {
+				add(l)
+			}
+			continue
+		}
+		i := c.FindLocation(Shortcut(n))
+		if i < 0 {
+			return nil, errors.New("Unknown location: " + Shortcut(n))
+		}
+		add(c.Locations[i])
+	}
+	return locs, nil
+}
+
+// renameMember replaces a location shortcut in all groups.
+func (c *Config) renameMember(shortcut, newShortcut string) {
+	for _, members := range c.Groups {
+		for i, m := range members {
+			if Shortcut(m) == shortcut {
+				members[i] = newShortcut
+			}
+		}
+	}
+}
+
+// groupsOnlyOf returns the groups that have no other member than shortcut.
+func (c *Config) groupsOnlyOf(shortcut string) []string {
+	var names []string
+	for _, name := range c.GroupNames() {
+		only := true
+		for _, m := range c.Groups[name] {
+			only = only && Shortcut(m) == shortcut
+		}
+		if only {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// removeMember deletes a location shortcut from all groups
+// and drops groups that became empty.
+func (c *Config) removeMember(shortcut string) {
+	for name, members := range c.Groups {
+		kept := members[:0]
+		for _, m := range members {
+			if Shortcut(m) != shortcut {
+				kept = append(kept, m)
+			}
+		}
+		if len(kept) == 0 {
+			delete(c.Groups, name)
+		} else {
+			c.Groups[name] = kept
+		}
+	}
+}
+
+func validGroupName(name string) error {
+	switch {
+	case name == "":
+		return errors.New("Group name is empty")
+	case strings.EqualFold(GroupPrefix+name, HereLocation):
+		return errors.New("Group name is reserved: " + name)
+	case strings.ContainsAny(name, " \t#@,"):
+		return errors.New("Group name must not contain spaces or any of #@,: " + name)
+	}
+	return nil
+}
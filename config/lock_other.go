@@ -0,0 +1,9 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package config
+
+// lockFile is a no-op on platforms without flock. Writes are still atomic.
+func lockFile(path string) (func(), error) {
+	return func() {}, nil
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/dbriemann/sunlens/geocode"
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// SchemaVersion is the version of the config file layout written by this program.
+// Files without a version are version 0.
+const SchemaVersion = 1
+
+// migrations[i] upgrades a raw config from version i to version i+1.
+var migrations = []func(raw map[string]interface{}) error{
+	migrateV0,
+}
+
+// DefaultConfig returns a config with default values for all settings
+// but without api key and locations.
+func DefaultConfig() *Config {
+	c := &Config{
+		Version:    SchemaVersion,
+		UnitFormat: "auto",
+		Language:   "en",
+		Geocoder:   geocode.Default,
+		HeatMap:    DefaultHeatMap(),
+	}
+	c.Here.CacheTime = 600
+	return c
+}
+
+// DefaultHeatMap returns the default temperature colors.
+func DefaultHeatMap() []utils.HeatColor {
+	return []utils.HeatColor{
+		utils.HeatColor{Temperature: -10, Color: utils.Color{R: 0, G: 0, B: 5}}, //blue
+		utils.HeatColor{Temperature: 0, Color: utils.Color{R: 0, G: 5, B: 5}},   //cyan
+		utils.HeatColor{Temperature: 10, Color: utils.Color{R: 0, G: 5, B: 0}},  //green
+		utils.HeatColor{Temperature: 20, Color: utils.Color{R: 5, G: 5, B: 0}},  //yellow
+		utils.HeatColor{Temperature: 30, Color: utils.Color{R: 5, G: 0, B: 0}},  //red
+	}
+}
+
+// readConfig reads the config file at path. Files of an older schema version
+// are migrated in memory only, see migrateFile. Settings missing in the file
+// get their default values.
+func readConfig(path string) (*Config, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	raw := map[string]interface{}{}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return nil, errors.New("Error in config file: " + path + " : " + err.Error())
+	}
+	version, err := rawVersion(raw)
+	if err != nil {
+		return nil, errors.New("Error in config file: " + path + " : " + err.Error())
+	}
+	if version > SchemaVersion {
+		return nil, fmt.Errorf("Config file %s has version %d, but this sunlens only supports up to version %d. Please update sunlens.", path, version, SchemaVersion)
+	}
+
+	migrated := b
+	if version < SchemaVersion {
+		for v := version; v < SchemaVersion; v++ {
+			if err := migrations[v](raw); err != nil {
+				return nil, fmt.Errorf("Could not migrate config file %s from version %d: %s", path, v, err.Error())
+			}
+			raw["Version"] = v + 1
+		}
+		if migrated, err = json.Marshal(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	c := DefaultConfig()
+	if err := json.Unmarshal(migrated, c); err != nil {
+		return nil, errors.New("Error in config file: " + path + " : " + err.Error())
+	}
+	c.applyDefaults()
+	c.fileVersion = version
+	return c, nil
+}
+
+// needsMigration reports whether c was read from a file of an older schema version.
+func (c *Config) needsMigration() bool {
+	return c.fileVersion < SchemaVersion
+}
+
+// migrateFile writes the migrated config c to path and keeps the original
+// file in a backup next to it. The caller must hold the lock of the file.
+func (c *Config) migrateFile(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, c.fileVersion)
+	if err := writeFileAtomic(backup, b, 0600); err != nil {
+		return errors.New("Could not back up config file before migration: " + err.Error())
+	}
+	if err := c.Save(path); err != nil {
+		return errors.New("Could not save migrated config file: " + err.Error())
+	}
+	fmt.Printf("Migrated config file %s to version %d. The old file was saved as %s\n", path, SchemaVersion, backup)
+	c.fileVersion = SchemaVersion
+	return nil
+}
+
+// applyDefaults replaces settings that are explicitly empty in the file.
+func (c *Config) applyDefaults() {
+	def := DefaultConfig()
+	if c.UnitFormat == "" {
+		c.UnitFormat = def.UnitFormat
+	}
+	if c.Language == "" {
+		c.Language = def.Language
+	}
+	if c.Geocoder == "" {
+		c.Geocoder = def.Geocoder
+	}
+	if len(c.HeatMap) == 0 {
+		c.HeatMap = def.HeatMap
+	}
+}
+
+func rawVersion(raw map[string]interface{}) (int, error) {
+	v, ok := raw["Version"]
+	if !ok || v == nil {
+		return 0, nil
+	}
+	f, ok := v.(float64)
+	if !ok || f < 0 || f != float64(int(f)) {
+		return 0, fmt.Errorf("invalid Version: %v", v)
+	}
+	return int(f), nil
+}
+
+// migrateV0 moves the single location of early config files
+// (top level City, Latitude and Longitude) into Locations.
+func migrateV0(raw map[string]interface{}) error {
+	city, _ := raw["City"].(string)
+	lat, hasLat := raw["Latitude"].(float64)
+	lng, hasLng := raw["Longitude"].(float64)
+	delete(raw, "City")
+	delete(raw, "Latitude")
+	delete(raw, "Longitude")
+	if !hasLat || !hasLng {
+		return nil
+	}
+
+	locs, _ := raw["Locations"].([]interface{})
+	for _, l := range locs {
+		m, _ := l.(map[string]interface{})
+		llat, _ := m["Latitude"].(float64)
+		llng, _ := m["Longitude"].(float64)
+		if utils.Distance(lat, lng, llat, llng) < NearbyDistance {
+			return nil
+		}
+	}
+
+	shortcut := "#home"
+	if name := strings.TrimSpace(strings.Split(city, ",")[0]); name != "" {
+		shortcut = Shortcut(strings.ToLower(name))
+	}
+	raw["Locations"] = append(locs, map[string]interface{}{
+		"City":      city,
+		"Latitude":  lat,
+		"Longitude": lng,
+		"Shortcut":  shortcut,
+	})
+	if len(locs) == 0 {
+		raw["DefaultLocation"] = 0
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ConfigEnv is the environment variable with the path of the config file.
+const ConfigEnv = "SUNLENS_CONFIG"
+
+// appDir is the name of sunlens' directory in the base directories.
+const appDir = "sunlens"
+
+// ConfigDir returns the directory of the config file, which is
+// $XDG_CONFIG_HOME/sunlens or ~/.config/sunlens.
+func ConfigDir() (string, error) {
+	return baseDir("XDG_CONFIG_HOME", ".config")
+}
+
+// CacheDir returns the directory for cached data that may be deleted any
+// time, which is $XDG_CACHE_HOME/sunlens or ~/.cache/sunlens.
+func CacheDir() (string, error) {
+	return baseDir("XDG_CACHE_HOME", ".cache")
+}
+
+// baseDir follows the XDG base directory specification: env must hold
+// an absolute path, otherwise the fallback below the home directory is used.
+func baseDir(env, fallback string) (string, error) {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return filepath.Join(dir, appDir), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return "", errors.New("Could not find the home directory, please set " + env + " or " + ConfigEnv)
+	}
+	return filepath.Join(home, fallback, appDir), nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// Profile is a named set of settings, e.g. for "work" or "sailing".
+// Empty fields keep the global setting.
+type Profile struct {
+	Provider   string            `json:",omitempty"`
+	UnitFormat string            `json:",omitempty"`
+	Language   string            `json:",omitempty"`
+	HeatMap    []utils.HeatColor `json:",omitempty"`
+	Panels     []string          `json:",omitempty"`
+	// Locations restricts the saved locations to these shortcuts and groups.
+	Locations []string `json:",omitempty"`
+	// DefaultLocation is the shortcut of the default location in this profile.
+	DefaultLocation string `json:",omitempty"`
+}
+
+// ProfileEnv is the environment variable selecting a profile.
+const ProfileEnv = "SUNLENS_PROFILE"
+
+// ApplyProfile replaces the global settings of c with the ones of the named profile.
+// The changed config is meant for rendering and should not be saved.
+func (c *Config) ApplyProfile(name string) error {
+	p, ok := c.Profiles[name]
+	if !ok {
+		return errors.New("Unknown profile: " + name)
+	}
+
+	if p.Provider != "" {
+		c.Provider = p.Provider
+	}
+	if p.UnitFormat != "" {
+		c.UnitFormat = p.UnitFormat
+	}
+	if p.Language != "" {
+		c.Language = p.Language
+	}
+	if len(p.HeatMap) > 0 {
+		c.HeatMap = p.HeatMap
+	}
+	if len(p.Panels) > 0 {
+		c.Panels = p.Panels
+	}
+
+	def, _ := c.Default()
+	if p.DefaultLocation != "" {
+		i := c.FindLocation(Shortcut(p.DefaultLocation))
+		if i < 0 {
+			return errors.New("Unknown default location in profile " + name + ": " + p.DefaultLocation)
+		}
+		def = c.Locations[i]
+	}
+	if len(p.Locations) > 0 {
+		locs, err := c.Expand(p.Locations)
+		if err != nil {
+			return errors.New("Profile " + name + ": " + err.Error())
+		}
+		c.Locations = locs
+	}
+	c.DefaultLocation = 0
+	for i, l := range c.Locations {
+		if l.Shortcut == def.Shortcut {
+			c.DefaultLocation = i
+		}
+	}
+	return nil
+}
+
+// ProfileNames returns the names of all profiles, sorted.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// renameProfileRefs replaces a location shortcut in the locations
+// and default locations of all profiles.
+func (c *Config) renameProfileRefs(shortcut, newShortcut string) {
+	for name, p := range c.Profiles {
+		for i, s := range p.Locations {
+			if !IsGroup(s) && Shortcut(s) == shortcut {
+				p.Locations[i] = newShortcut
+			}
+		}
+		if p.DefaultLocation != "" && Shortcut(p.DefaultLocation) == shortcut {
+			p.DefaultLocation = newShortcut
+		}
+		c.Profiles[name] = p
+	}
+}
+
+// removeProfileRefs deletes a location shortcut and the given groups from all
+// profiles. A profile whose default location is removed falls back to the
+// global one. Nothing is changed if a profile would be left without locations,
+// as that would show all of them.
+func (c *Config) removeProfileRefs(shortcut string, groups []string) error {
+	removed := func(s string) bool {
+		if IsGroup(s) {
+			return contains(groups, groupName(s))
+		}
+		return Shortcut(s) == shortcut
+	}
+
+	names := c.ProfileNames()
+	kept := map[string][]string{}
+	for _, name := range names {
+		p := c.Profiles[name]
+		if len(p.Locations) == 0 {
+			continue
+		}
+		for _, s := range p.Locations {
+			if !removed(s) {
+				kept[name] = append(kept[name], s)
+			}
+		}
+		if len(kept[name]) == 0 {
+			return errors.New("Profile " + name + " would be left without locations")
+		}
+	}
+
+	for _, name := range names {
+		p := c.Profiles[name]
+		if len(p.Locations) > 0 {
+			p.Locations = kept[name]
+		}
+		if p.DefaultLocation != "" && Shortcut(p.DefaultLocation) == shortcut {
+			p.DefaultLocation = ""
+		}
+		c.Profiles[name] = p
+	}
+	return nil
+}
@@ -0,0 +1,327 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/dbriemann/sunlens/coords"
+	"github.com/dbriemann/sunlens/geocode"
+	"github.com/dbriemann/sunlens/locate"
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// Resolver finds the location for a command line argument. It looks at saved
+// shortcuts and aliases first, then at the local geocode cache, and only asks
+// the remote geocoder as a last resort. If nothing is found, close saved
+// names are suggested.
+type Resolver struct {
+	Config   *Config
+	Cache    *GeoCache
+	Geocoder geocode.Geocoder // remote geocoder, made by NewGeocoder on first use if nil
+	Reverser geocode.Reverser // names coordinates, Geocoder is used if nil
+	Choose   Chooser          // picks between ambiguous geocoding results
+	Here     *locate.Locator  // detects the position of the "@here" location
+
+	// NewGeocoder creates the geocoder only when it is needed, e.g. the
+	// geonames one reads its whole data file.
+	NewGeocoder func() (geocode.Geocoder, error)
+}
+
+// HereLocation is the pseudo location argument for the current position.
+const HereLocation = "@here"
+
+// ResolveAll resolves every argument like Resolve, but expands groups to their
+// members. The returned save flags tell which locations are newly geocoded.
+func (r *Resolver) ResolveAll(args []string) ([]Location, []bool, error) {
+	var locs []Location
+	var save []bool
+	for _, arg := range args {
+		if IsGroup(arg) && r.Config != nil {
+			members, err := r.Config.Group(arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, l := range members {
+				locs, save = append(locs, l), append(save, false)
+			}
+			continue
+		}
+		loc, s, err := r.Resolve(arg)
+		if err != nil {
+			return nil, nil, err
+		}
+		locs, save = append(locs, loc), append(save, s)
+	}
+	return locs, save, nil
+}
+
+// Resolve returns the location for arg and whether it is a newly geocoded
+// place that should be saved in the config. Coordinates, grid references and
+// word aliases are parsed locally and never saved.
+func (r *Resolver) Resolve(arg string) (Location, bool, error) {
+	if strings.EqualFold(strings.TrimSpace(arg), HereLocation) {
+		loc, err := r.here()
+		return loc, false, err
+	}
+	if IsGroup(arg) {
+		return Location{}, false, errors.New("A single location is needed here, not the group " + arg)
+	}
+
+	var ref *coords.Point
+	if r.Config != nil {
+		if loc, ok := r.Config.MatchLocation(arg); ok {
+			return loc, false, nil
+		}
+		if strings.HasPrefix(arg, WordAliasPrefix) {
+			loc, err := r.Config.WordAlias(arg)
+			return loc, false, err
+		}
+		if def, ok := r.Config.Default(); ok {
+			ref = &coords.Point{Latitude: def.Latitude, Longitude: def.Longitude}
+		}
+	}
+
+	if p, _, err := coords.Parse(arg, ref); err != coords.ErrUnknownFormat {
+		if err != nil {
+			return Location{}, false, errors.New("Invalid coordinates: " + arg + "\nError: " + err.Error())
+		}
+		return Location{
+			City:      r.placeName(p.Latitude, p.Longitude),
+			Latitude:  p.Latitude,
+			Longitude: p.Longitude,
+			Shortcut:  "#" + strings.TrimSpace(arg),
+		}, false, nil
+	}
+
+	if r.Cache != nil {
+		if loc, ok := r.Cache.Get(arg); ok {
+			return loc, true, nil
+		}
+	}
+
+	geocoder, err := r.geocoder()
+	if err != nil {
+		return Location{}, false, err
+	}
+	if geocoder == nil {
+		return Location{}, false, r.suggest(arg, errors.New("Unknown location: "+arg))
+	}
+	loc, err := NewLocation(geocoder, arg, r.Choose)
+	if err != nil {
+		return loc, false, r.suggest(arg, err)
+	}
+	if r.Cache != nil {
+		r.Cache.Put(arg, loc)
+	}
+	return loc, true, nil
+}
+
+// geocoder returns the remote geocoder, creating it on first use.
+func (r *Resolver) geocoder() (geocode.Geocoder, error) {
+	if r.Geocoder == nil && r.NewGeocoder != nil {
+		g, err := r.NewGeocoder()
+		if err != nil {
+			return nil, err
+		}
+		r.Geocoder = g
+	}
+	return r.Geocoder, nil
+}
+
+// suggest adds a saved location with a name close to arg to the error
+// err. Misspelled names are never used without asking, they might be
+// the names of other places.
+func (r *Resolver) suggest(arg string, err error) error {
+	if r.Config == nil {
+		return err
+	}
+	if l, ok := r.Config.SuggestLocation(arg); ok {
+		return errors.New(err.Error() + "\nDid you mean " + l.Shortcut + "?")
+	}
+	return err
+}
+
+// here returns the current position. If it cannot be detected
+// the default location is used instead.
+func (r *Resolver) here() (Location, error) {
+	var err error
+	if r.Here != nil {
+		var fix locate.Fix
+		if fix, err = r.Here.Locate(); err == nil {
+			city := fix.City
+			if fix.Source != locate.SourceIP || city == "" {
+				city = r.placeName(fix.Latitude, fix.Longitude)
+			}
+			return Location{
+				City:      city,
+				Latitude:  fix.Latitude,
+				Longitude: fix.Longitude,
+				Shortcut:  HereLocation,
+			}, nil
+		}
+	}
+
+	if r.Config != nil {
+		if def, ok := r.Config.Default(); ok {
+			if err != nil {
+				fmt.Println(err.Error() + "\nUsing default location.")
+			}
+			return def, nil
+		}
+	}
+	if err == nil {
+		err = errors.New("Could not detect current position")
+	}
+	return Location{}, err
+}
+
+// placeName describes a position relative to the nearest known place.
+// It falls back to the coordinates if reverse geocoding is not possible.
+func (r *Resolver) placeName(lat, lng float64) string {
+	rev := r.Reverser
+	if rev == nil {
+		g, _ := r.geocoder()
+		rev, _ = g.(geocode.Reverser)
+	}
+	if rev != nil {
+		if name, err := geocode.ReverseName(rev, lat, lng); err == nil {
+			return name
+		}
+	}
+	return fmt.Sprintf("%.5f, %.5f", lat, lng)
+}
+
+// WordAliasPrefix marks a what3words style alias like "///table.chair.lamp".
+const WordAliasPrefix = "///"
+
+// WordAlias returns the location for a user defined word alias.
+func (c *Config) WordAlias(arg string) (Location, error) {
+	words := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(arg), WordAliasPrefix))
+	p, ok := c.WordAliases[words]
+	if !ok {
+		return Location{}, errors.New("Unknown word alias: " + arg)
+	}
+	return Location{
+		City:      words,
+		Latitude:  p.Latitude,
+		Longitude: p.Longitude,
+		Shortcut:  WordAliasPrefix + words,
+	}, nil
+}
+
+// Default returns the default location if there is one.
+func (c *Config) Default() (Location, bool) {
+	if c.DefaultLocation < 0 || c.DefaultLocation >= len(c.Locations) {
+		return Location{}, false
+	}
+	return c.Locations[c.DefaultLocation], true
+}
+
+// MatchLocation finds a saved location by shortcut or alias. Names are
+// compared case-insensitively.
+func (c *Config) MatchLocation(name string) (Location, bool) {
+	name = strings.TrimPrefix(strings.TrimSpace(name), "#")
+	if name == "" {
+		return Location{}, false
+	}
+
+	for _, l := range c.Locations {
+		for _, n := range l.names() {
+			if strings.EqualFold(n, name) {
+				return l, true
+			}
+		}
+	}
+	return Location{}, false
+}
+
+// SuggestLocation finds the single saved location whose shortcut or alias
+// is a close misspelling of name.
+func (c *Config) SuggestLocation(name string) (Location, bool) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "#"))
+	if name == "" {
+		return Location{}, false
+	}
+
+	// allow one typo for short names and two for longer ones
+	maxDist := 1
+	if len(name) > 5 {
+		maxDist = 2
+	}
+	best, bestDist, ties := -1, maxDist+1, 0
+	for i, l := range c.Locations {
+		for _, n := range l.names() {
+			d := utils.Levenshtein(strings.ToLower(n), name)
+			if d < bestDist {
+				best, bestDist, ties = i, d, 0
+			} else if d == bestDist && i != best {
+				ties++
+			}
+		}
+	}
+	if best >= 0 && ties == 0 {
+		return c.Locations[best], true
+	}
+	return Location{}, false
+}
+
+// names returns the shortcut without "#" and all aliases of l.
+func (l Location) names() []string {
+	return append([]string{strings.TrimPrefix(l.Shortcut, "#")}, l.Aliases...)
+}
+
+// GeoCache remembers geocoding results in a json file so repeated
+// queries for places that are not saved work offline.
+type GeoCache struct {
+	path    string
+	mu      sync.Mutex
+	entries map[string]Location
+}
+
+// LoadGeoCache reads the geocode cache at path. A missing file yields an empty cache,
+// an empty path one that is never written.
+func LoadGeoCache(path string) (*GeoCache, error) {
+	gc := &GeoCache{path: path, entries: make(map[string]Location)}
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return gc, nil
+	} else if err != nil {
+		return gc, err
+	}
+	if err := json.Unmarshal(b, &gc.entries); err != nil {
+		return gc, err
+	}
+	return gc, nil
+}
+
+// Get returns the cached location for query.
+func (gc *GeoCache) Get(query string) (Location, bool) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	loc, ok := gc.entries[cacheKey(query)]
+	return loc, ok
+}
+
+// Put stores loc for query and writes the cache file. Failing to write
+// the cache is not fatal, so errors are ignored.
+func (gc *GeoCache) Put(query string, loc Location) {
+	gc.mu.Lock()
+	defer gc.mu.Unlock()
+	gc.entries[cacheKey(query)] = loc
+	if gc.path == "" {
+		return
+	}
+
+	if j, err := json.MarshalIndent(gc.entries, "", "\t"); err == nil {
+		writeFileAtomic(gc.path, j, 0600)
+	}
+}
+
+func cacheKey(query string) string {
+	return strings.ToLower(strings.Join(strings.Fields(query), " "))
+}
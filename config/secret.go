@@ -0,0 +1,141 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// ApiKeyEnv is the environment variable that takes precedence over all other api key sources.
+const ApiKeyEnv = "SUNLENS_API_KEY"
+
+// sources of the api key
+const (
+	KeySourceEnv     = "env"
+	KeySourceFile    = "file"
+	KeySourceCommand = "command"
+	KeySourceConfig  = "config"
+)
+
+// apiKeyCommandTimeout limits how long ApiKeyCommand may take, e.g. to unlock a password store.
+const apiKeyCommandTimeout = 30 * time.Second
+
+// resolveApiKey sets c.ApiKey from the first available source:
+//
+//  1. the SUNLENS_API_KEY environment variable
+//  2. the file ApiKeyFile, which must not be readable by group or others
+//  3. the first line printed by ApiKeyCommand
+//  4. ApiKey in the config file
+//
+// The key from the config file is remembered, so Save never writes
+// a key from another source to the file.
+func (c *Config) resolveApiKey() error {
+	c.fileApiKey = c.ApiKey
+	c.apiKeySource = KeySourceConfig
+
+	if key := strings.TrimSpace(os.Getenv(ApiKeyEnv)); key != "" {
+		c.ApiKey, c.apiKeySource = key, KeySourceEnv
+		return nil
+	}
+
+	if c.ApiKeyFile != "" {
+		key, err := readKeyFile(expandHome(c.ApiKeyFile))
+		if err != nil {
+			return err
+		}
+		c.ApiKey, c.apiKeySource = key, KeySourceFile
+		return nil
+	}
+
+	if c.ApiKeyCommand != "" {
+		key, err := runKeyCommand(c.ApiKeyCommand)
+		if err != nil {
+			return err
+		}
+		c.ApiKey, c.apiKeySource = key, KeySourceCommand
+		return nil
+	}
+	return nil
+}
+
+// ApiKeySource tells where the api key in use came from.
+func (c *Config) ApiKeySource() string {
+	if c.apiKeySource == "" {
+		return KeySourceConfig
+	}
+	return c.apiKeySource
+}
+
+func readKeyFile(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", errors.New("Cannot read ApiKeyFile: " + err.Error())
+	}
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0077 != 0 {
+		return "", fmt.Errorf("ApiKeyFile %s is accessible by other users (mode %04o). Please run: chmod 600 %s", path, info.Mode().Perm(), path)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.New("Cannot read ApiKeyFile: " + err.Error())
+	}
+	key := strings.TrimSpace(firstLine(string(b)))
+	if key == "" {
+		return "", errors.New("ApiKeyFile is empty: " + path)
+	}
+	return key, nil
+}
+
+func runKeyCommand(command string) (string, error) {
+	cmd := exec.Command("sh", "-c", command)
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = os.Stderr
+	cmd.Stdin = os.Stdin // allows password prompts, e.g. by gpg
+
+	if err := cmd.Start(); err != nil {
+		return "", errors.New("Cannot run ApiKeyCommand: " + err.Error())
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			return "", errors.New("ApiKeyCommand failed: " + err.Error())
+		}
+	case <-time.After(apiKeyCommandTimeout):
+		cmd.Process.Kill()
+		return "", errors.New("ApiKeyCommand did not finish within " + apiKeyCommandTimeout.String())
+	}
+
+	key := strings.TrimSpace(firstLine(stdout.String()))
+	if key == "" {
+		return "", errors.New("ApiKeyCommand printed no key: " + command)
+	}
+	return key, nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// expandHome replaces a leading "~/" with the home directory.
+func expandHome(path string) string {
+	if strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return home + path[1:]
+		}
+	}
+	return path
+}
@@ -0,0 +1,285 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dbriemann/sunlens/forecastio"
+	"github.com/dbriemann/sunlens/geocode"
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// Problem is a single invalid setting in a config.
+type Problem struct {
+	Path    string // json path of the setting, e.g. HeatMap[2].Color.R
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// ValidationError lists all problems found in a config file.
+type ValidationError struct {
+	File     string
+	Problems []Problem
+}
+
+func (e *ValidationError) Error() string {
+	msg := fmt.Sprintf("Invalid config file %s:", e.File)
+	for _, p := range e.Problems {
+		msg += "\n  " + p.String()
+	}
+	return msg + "\nRun \"sunlens config check\" after fixing it."
+}
+
+// unit formats supported by the forecast api
+var unitFormats = []string{forecastio.AUTO, forecastio.CA, forecastio.SI, forecastio.UK, forecastio.US}
+
+// colorMax is the highest value of a color channel, see utils.Color.
+const colorMax = 5
+
+// Validate checks all settings of c and returns every problem found.
+func (c *Config) Validate() []Problem {
+	v := &validator{}
+
+	if c.Provider == "" && c.BaseURL == "" && c.ApiKey == "" {
+		v.add("ApiKey", "is empty, please set your api key here, in ApiKeyFile, ApiKeyCommand or "+ApiKeyEnv)
+	}
+	v.provider("Provider", c.Provider, c)
+	providers := make([]string, 0, len(c.Providers))
+	for name := range c.Providers {
+		providers = append(providers, name)
+	}
+	sort.Strings(providers)
+	for _, name := range providers {
+		if p := c.Providers[name]; p.BaseURL == "" {
+			v.add(fmt.Sprintf("Providers.%s.BaseURL", name), "is empty")
+		}
+	}
+	v.unitFormat("UnitFormat", c.UnitFormat)
+	v.language("Language", c.Language)
+	v.heatMap("HeatMap", c.HeatMap)
+	v.panels("Panels", c.Panels)
+	if c.Glyphs != "" && !contains(GlyphSets, c.Glyphs) {
+		v.add("Glyphs", fmt.Sprintf("unknown glyph set %q, use one of: %s", c.Glyphs, strings.Join(GlyphSets, ", ")))
+	}
+
+	switch strings.ToLower(c.Geocoder) {
+	case "", geocode.Google, geocode.Nominatim, geocode.Photon:
+	case geocode.GeoNames:
+		if c.GazetteerPath == "" {
+			v.add("GazetteerPath", "is needed by the geonames geocoder")
+		}
+	default:
+		v.add("Geocoder", fmt.Sprintf("unknown geocoder %q, use one of: google, nominatim, photon, geonames", c.Geocoder))
+	}
+
+	if len(c.Locations) == 0 {
+		v.add("Locations", "no locations saved, please add at least one")
+	} else if c.DefaultLocation < 0 || c.DefaultLocation >= len(c.Locations) {
+		v.add("DefaultLocation", fmt.Sprintf("%d is out of range, there are %d locations (0 to %d)", c.DefaultLocation, len(c.Locations), len(c.Locations)-1))
+	}
+
+	seen := map[string]int{}
+	for i, l := range c.Locations {
+		path := fmt.Sprintf("Locations[%d]", i)
+		if l.Shortcut == "" {
+			v.add(path+".Shortcut", "is empty")
+		} else if j, ok := seen[strings.ToLower(l.Shortcut)]; ok {
+			v.add(path+".Shortcut", fmt.Sprintf("%q is already used by Locations[%d]", l.Shortcut, j))
+		} else {
+			seen[strings.ToLower(l.Shortcut)] = i
+		}
+		v.coordinates(path, l.Latitude, l.Longitude)
+		if l.UnitFormat != "" {
+			v.unitFormat(path+".UnitFormat", l.UnitFormat)
+		}
+		if l.Language != "" {
+			v.language(path+".Language", l.Language)
+		}
+		if len(l.HeatMap) > 0 {
+			v.heatMap(path+".HeatMap", l.HeatMap)
+		}
+		v.provider(path+".Provider", l.Provider, c)
+		if l.Timezone != "" {
+			if _, err := time.LoadLocation(l.Timezone); err != nil {
+				v.add(path+".Timezone", fmt.Sprintf("unknown timezone %q", l.Timezone))
+			}
+		}
+	}
+
+	for _, name := range c.ProfileNames() {
+		p := c.Profiles[name]
+		path := "Profiles." + name
+		v.provider(path+".Provider", p.Provider, c)
+		if p.UnitFormat != "" {
+			v.unitFormat(path+".UnitFormat", p.UnitFormat)
+		}
+		if p.Language != "" {
+			v.language(path+".Language", p.Language)
+		}
+		if len(p.HeatMap) > 0 {
+			v.heatMap(path+".HeatMap", p.HeatMap)
+		}
+		v.panels(path+".Panels", p.Panels)
+		for i, s := range p.Locations {
+			if IsGroup(s) {
+				if _, ok := c.Groups[groupName(s)]; !ok {
+					v.add(fmt.Sprintf("%s.Locations[%d]", path, i), fmt.Sprintf("unknown group %q", s))
+				}
+			} else if c.FindLocation(Shortcut(s)) < 0 {
+				v.add(fmt.Sprintf("%s.Locations[%d]", path, i), fmt.Sprintf("unknown location %q", s))
+			}
+		}
+		if p.DefaultLocation != "" && c.FindLocation(Shortcut(p.DefaultLocation)) < 0 {
+			v.add(path+".DefaultLocation", fmt.Sprintf("unknown location %q", p.DefaultLocation))
+		}
+	}
+
+	for _, name := range c.GroupNames() {
+		path := "Groups." + name
+		if err := validGroupName(name); err != nil {
+			v.add(path, err.Error())
+		}
+		if len(c.Groups[name]) == 0 {
+			v.add(path, "has no locations")
+		}
+		for i, s := range c.Groups[name] {
+			if c.FindLocation(Shortcut(s)) < 0 {
+				v.add(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("unknown location %q", s))
+			}
+		}
+	}
+
+	aliases := make([]string, 0, len(c.WordAliases))
+	for words := range c.WordAliases {
+		aliases = append(aliases, words)
+	}
+	sort.Strings(aliases)
+	for _, words := range aliases {
+		p := c.WordAliases[words]
+		v.coordinates("WordAliases."+words, p.Latitude, p.Longitude)
+	}
+
+	return v.problems
+}
+
+type validator struct {
+	problems []Problem
+}
+
+func (v *validator) add(path, msg string) {
+	v.problems = append(v.problems, Problem{Path: path, Message: msg})
+}
+
+func (v *validator) unitFormat(path, unit string) {
+	if !contains(unitFormats, unit) {
+		v.add(path, fmt.Sprintf("unknown unit format %q, use one of: %s", unit, strings.Join(unitFormats, ", ")))
+	}
+}
+
+func (v *validator) language(path, lang string) {
+	if !contains(forecastio.Languages, lang) {
+		v.add(path, fmt.Sprintf("unknown language %q, use one of: %s", lang, strings.Join(forecastio.Languages, ", ")))
+	}
+}
+
+// provider checks that the named provider is known and that there is an api
+// key for it. Without a provider BaseURL is used, e.g. a team proxy, which
+// needs no key of its clients.
+func (v *validator) provider(path, name string, c *Config) {
+	if name == "" {
+		return
+	}
+	p, ok := c.Providers[name]
+	if !ok {
+		if _, ok := forecastio.Providers[name]; !ok {
+			v.add(path, fmt.Sprintf("unknown provider %q, add it to Providers", name))
+			return
+		}
+	}
+	if c.ApiKey == "" && p.ApiKey == "" {
+		where := "ApiKey, ApiKeyFile, ApiKeyCommand or " + ApiKeyEnv
+		if ok {
+			where = "ApiKey, ApiKeyFile, ApiKeyCommand, " + ApiKeyEnv + " or Providers." + name + ".ApiKey"
+		}
+		v.add(path, fmt.Sprintf("provider %q needs an api key, please set %s", name, where))
+	}
+}
+
+func (v *validator) panels(path string, panels []string) {
+	for i, p := range panels {
+		if !contains(Panels, p) {
+			v.add(fmt.Sprintf("%s[%d]", path, i), fmt.Sprintf("unknown panel %q, use one of: %s", p, strings.Join(Panels, ", ")))
+		}
+	}
+}
+
+func (v *validator) coordinates(path string, lat, lng float64) {
+	if lat < -90 || lat > 90 {
+		v.add(path+".Latitude", fmt.Sprintf("%g is out of range (-90 to 90)", lat))
+	}
+	if lng < -180 || lng > 180 {
+		v.add(path+".Longitude", fmt.Sprintf("%g is out of range (-180 to 180)", lng))
+	}
+}
+
+// heatMap checks that temperatures are strictly increasing and that all
+// colors are within range, as the color interpolation would overflow otherwise.
+func (v *validator) heatMap(path string, hm []utils.HeatColor) {
+	if len(hm) == 0 {
+		v.add(path, "needs at least one color")
+		return
+	}
+	for i, hc := range hm {
+		p := fmt.Sprintf("%s[%d]", path, i)
+		if i > 0 && hc.Temperature <= hm[i-1].Temperature {
+			v.add(p+".Temperature", fmt.Sprintf("%g must be greater than the previous temperature %g, sort the heatmap by temperature", hc.Temperature, hm[i-1].Temperature))
+		}
+		for _, ch := range []struct {
+			name  string
+			value uint8
+		}{{"R", hc.Color.R}, {"G", hc.Color.G}, {"B", hc.Color.B}} {
+			if ch.value > colorMax {
+				v.add(fmt.Sprintf("%s.Color.%s", p, ch.name), fmt.Sprintf("%d is out of range (0 to %d)", ch.value, colorMax))
+			}
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, l := range list {
+		if l == s {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckFile reads and validates the config file at path
+// including the overrides by environment variables. The file is
+// never changed, an older schema version is reported as a problem.
+func CheckFile(path string) ([]Problem, error) {
+	c, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	var problems []Problem
+	if c.needsMigration() {
+		problems = append(problems, Problem{Path: "Version", Message: fmt.Sprintf("file has version %d and needs migration, the next sunlens command migrates it to version %d", c.fileVersion, SchemaVersion)})
+	}
+	if err := c.applyEnv(); err != nil {
+		return append(problems, Problem{Path: "environment", Message: err.Error()}), nil
+	}
+	if err := c.resolveApiKey(); err != nil {
+		path := "ApiKeyFile"
+		if c.ApiKeyFile == "" {
+			path = "ApiKeyCommand"
+		}
+		return append(problems, Problem{Path: path, Message: err.Error()}), nil
+	}
+	return append(problems, c.Validate()...), nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// current holds the *Config in use, see Current.
+var current atomic.Value
+
+// Current returns the config in use or nil if none is loaded yet. The config
+// is shared between goroutines and must not be modified. Changes are made to
+// a new Config which replaces it as a whole with SetCurrent.
+func Current() *Config {
+	c, _ := current.Load().(*Config)
+	return c
+}
+
+// SetCurrent makes c the config in use.
+func SetCurrent(c *Config) {
+	current.Store(c)
+}
+
+// DefaultWatchInterval is the time between two checks of the config file.
+const DefaultWatchInterval = 2 * time.Second
+
+// Watcher reloads the config file when it changes. A new config is only
+// swapped in if it is valid, otherwise the previous one stays in use.
+type Watcher struct {
+	Path     string
+	Profile  string        // profile applied to every loaded config, none if empty
+	Interval time.Duration // DefaultWatchInterval if zero
+	Logger   *log.Logger   // the standard logger if nil
+	OnChange func(c *Config)
+
+	modTime time.Time
+	size    int64
+}
+
+// NewWatcher loads the config file at path and makes it the current config.
+func NewWatcher(path, profile string) (*Watcher, error) {
+	w := &Watcher{Path: path, Profile: profile}
+	info, statErr := os.Stat(path)
+	c, err := w.load()
+	if err != nil {
+		return nil, err
+	}
+	if statErr == nil {
+		w.modTime, w.size = info.ModTime(), info.Size()
+	}
+	SetCurrent(c)
+	return w, nil
+}
+
+func (w *Watcher) load() (*Config, error) {
+	c, err := LoadConfig(w.Path, Location{})
+	if err != nil {
+		return nil, err
+	}
+	if w.Profile != "" {
+		if err := c.ApplyProfile(w.Profile); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Check reloads the config if the file was modified since the last check
+// and reports whether a new config is in use. It must not be called
+// concurrently, Run does so periodically.
+func (w *Watcher) Check() (bool, error) {
+	info, err := os.Stat(w.Path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().Equal(w.modTime) && info.Size() == w.size {
+		return false, nil
+	}
+	// remember the file before reading it, so a change while
+	// loading is picked up by the next check and an invalid
+	// edit is only reported once
+	w.modTime, w.size = info.ModTime(), info.Size()
+
+	c, err := w.load()
+	if err != nil {
+		return false, err
+	}
+	SetCurrent(c)
+	if w.OnChange != nil {
+		w.OnChange(c)
+	}
+	return true, nil
+}
+
+// Run checks the config file until stop is closed. A nil stop runs forever.
+func (w *Watcher) Run(stop <-chan struct{}) {
+	interval := w.Interval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+	logger := w.Logger
+	if logger == nil {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			changed, err := w.Check()
+			if err != nil {
+				logger.Println("Config not reloaded, keeping the previous one: " + err.Error())
+			} else if changed {
+				logger.Println("Reloaded config: " + w.Path)
+			}
+		}
+	}
+}
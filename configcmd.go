@@ -0,0 +1,54 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/dbriemann/sunlens/config"
+)
+
+const configUsage = `Usage: sunlens config <command>
+
+Commands:
+  init     create the config file, interactively or from flags (see init -h)
+  check    validate the config file and list all problems`
+
+// runConfig handles commands working on the config file itself.
+func runConfig(args []string) {
+	if len(args) == 0 {
+		fmt.Println(configUsage)
+		os.Exit(0)
+	}
+
+	switch args[0] {
+	case "init":
+		configInit(args[1:])
+	case "check":
+		configCheck()
+	default:
+		fmt.Println(errors.New("Unknown command: " + args[0] + "\n\n" + configUsage))
+		os.Exit(0)
+	}
+}
+
+// configCheck prints every problem of the config file. It exits with
+// status 1 if there are any, so it can be used in scripts.
+func configCheck() {
+	path := configFile()
+	problems, err := config.CheckFile(path)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+	if len(problems) == 0 {
+		fmt.Println(path + ": OK")
+		return
+	}
+
+	fmt.Printf("%s: %d problem(s)\n", path, len(problems))
+	for _, p := range problems {
+		fmt.Println("  " + p.String())
+	}
+	os.Exit(1)
+}
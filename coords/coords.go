@@ -0,0 +1,251 @@
+// Package coords parses locations given as coordinates or grid references
+// so they can be used without asking a geocoder.
+package coords
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// supported input formats
+const (
+	Decimal    = "decimal"    // 49.8717,8.6503
+	DMS        = "dms"        // 49°52'18"N 8°39'1"E
+	Geohash    = "geohash"    // gh:u0yjjd6
+	PlusCode   = "pluscode"   // 8FWCVMCX+V5 or VMCX+V5 near a reference point
+	Maidenhead = "maidenhead" // mh:JN49fu
+)
+
+// ErrUnknownFormat is returned if the input is not in any supported format.
+var ErrUnknownFormat = errors.New("unknown coordinate format")
+
+// Point is a position in decimal degrees.
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Parse reads s in any supported format and returns the position and the
+// name of the detected format. The reference point ref is needed to recover
+// short plus codes and may be nil otherwise. Geohashes need a "gh:" or
+// "geohash:" prefix and Maidenhead locators a "mh:" or "maidenhead:" one,
+// as they cannot be told apart from place names and postcodes like "AB12".
+func Parse(s string, ref *Point) (Point, string, error) {
+	s = strings.TrimSpace(s)
+
+	if h := trimPrefixFold(s, "geohash:", "gh:"); h != s {
+		p, err := ParseGeohash(h)
+		return p, Geohash, err
+	}
+	if m := trimPrefixFold(s, "maidenhead:", "mh:"); m != s {
+		p, err := ParseMaidenhead(m)
+		return p, Maidenhead, err
+	}
+	if decimalRe.MatchString(s) {
+		p, err := ParseDecimal(s)
+		return p, Decimal, err
+	}
+	if plusCodeRe.MatchString(s) {
+		p, err := ParsePlusCode(s, ref)
+		return p, PlusCode, err
+	}
+	if p, err := ParseDMS(s); err == nil {
+		return p, DMS, nil
+	}
+	return Point{}, "", ErrUnknownFormat
+}
+
+func trimPrefixFold(s string, prefixes ...string) string {
+	for _, p := range prefixes {
+		if len(s) > len(p) && strings.EqualFold(s[:len(p)], p) {
+			return s[len(p):]
+		}
+	}
+	return s
+}
+
+func (p Point) valid() error {
+	if math.IsNaN(p.Latitude) || p.Latitude < -90 || p.Latitude > 90 {
+		return errors.New("latitude out of range: " + strconv.FormatFloat(p.Latitude, 'f', -1, 64))
+	}
+	if math.IsNaN(p.Longitude) || p.Longitude < -180 || p.Longitude > 180 {
+		return errors.New("longitude out of range: " + strconv.FormatFloat(p.Longitude, 'f', -1, 64))
+	}
+	return nil
+}
+
+var decimalRe = regexp.MustCompile(`^([-+]?\d+(?:\.\d+)?)\s*[,;\s]\s*([-+]?\d+(?:\.\d+)?)$`)
+
+// ParseDecimal reads "lat,lng" in decimal degrees. The separator may
+// also be a semicolon or white space.
+func ParseDecimal(s string) (Point, error) {
+	m := decimalRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Point{}, ErrUnknownFormat
+	}
+	lat, _ := strconv.ParseFloat(m[1], 64)
+	lng, _ := strconv.ParseFloat(m[2], 64)
+	p := Point{Latitude: lat, Longitude: lng}
+	return p, p.valid()
+}
+
+// dmsReplacer turns degree, minute and second marks into white space
+// and isolates hemisphere letters.
+var dmsReplacer = strings.NewReplacer(
+	"°", " ", "º", " ", "'", " ", "′", " ", "\"", " ", "″", " ", ",", " ",
+	"N", " N ", "S", " S ", "E", " E ", "W", " W ",
+)
+
+// ParseDMS reads degrees with optional minutes and seconds and hemisphere
+// letters before or after each axis, like 49°52'18"N 8°39'1"E or N 49 52.3 E 8 39.0.
+func ParseDMS(s string) (Point, error) {
+	tokens := strings.Fields(dmsReplacer.Replace(strings.ToUpper(strings.TrimSpace(s))))
+	if len(tokens) < 4 {
+		return Point{}, ErrUnknownFormat
+	}
+
+	// group the numbers of each axis with their hemisphere letter
+	type axis struct {
+		hemi   string
+		values []float64
+	}
+	var axes []axis
+	leading := isHemisphere(tokens[0])
+	cur := axis{}
+	for _, t := range tokens {
+		if isHemisphere(t) {
+			if leading {
+				if cur.hemi != "" {
+					axes = append(axes, cur)
+				}
+				cur = axis{hemi: t}
+			} else {
+				cur.hemi = t
+				axes = append(axes, cur)
+				cur = axis{}
+			}
+			continue
+		}
+		v, err := strconv.ParseFloat(t, 64)
+		if err != nil || v < 0 {
+			return Point{}, ErrUnknownFormat
+		}
+		cur.values = append(cur.values, v)
+	}
+	if leading {
+		axes = append(axes, cur)
+	} else if len(cur.values) > 0 {
+		return Point{}, ErrUnknownFormat
+	}
+	if len(axes) != 2 {
+		return Point{}, ErrUnknownFormat
+	}
+
+	var p Point
+	var haveLat, haveLng bool
+	for _, a := range axes {
+		if len(a.values) < 1 || len(a.values) > 3 {
+			return Point{}, ErrUnknownFormat
+		}
+		v := 0.0
+		for i, part := range a.values {
+			if i > 0 && part >= 60 {
+				return Point{}, errors.New("minutes and seconds must be below 60: " + s)
+			}
+			v += part / math.Pow(60, float64(i))
+		}
+
+		switch a.hemi {
+		case "N":
+			p.Latitude, haveLat = v, true
+		case "S":
+			p.Latitude, haveLat = -v, true
+		case "E":
+			p.Longitude, haveLng = v, true
+		case "W":
+			p.Longitude, haveLng = -v, true
+		}
+	}
+	if !haveLat || !haveLng {
+		return Point{}, ErrUnknownFormat
+	}
+	return p, p.valid()
+}
+
+func isHemisphere(t string) bool {
+	return t == "N" || t == "S" || t == "E" || t == "W"
+}
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// ParseGeohash decodes a geohash to the center of its cell.
+func ParseGeohash(s string) (Point, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if s == "" {
+		return Point{}, ErrUnknownFormat
+	}
+
+	latLo, latHi := -90.0, 90.0
+	lngLo, lngHi := -180.0, 180.0
+	even := true
+	for _, c := range s {
+		v := strings.IndexRune(geohashAlphabet, c)
+		if v < 0 {
+			return Point{}, errors.New("invalid geohash character: " + string(c))
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := v&(1<<uint(bit)) != 0
+			if even {
+				mid := (lngLo + lngHi) / 2
+				if set {
+					lngLo = mid
+				} else {
+					lngHi = mid
+				}
+			} else {
+				mid := (latLo + latHi) / 2
+				if set {
+					latLo = mid
+				} else {
+					latHi = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return Point{Latitude: (latLo + latHi) / 2, Longitude: (lngLo + lngHi) / 2}, nil
+}
+
+var maidenheadRe = regexp.MustCompile(`^(?i)[A-R]{2}[0-9]{2}(?:[A-X]{2}(?:[0-9]{2})?)?$`)
+
+// ParseMaidenhead decodes a 4, 6 or 8 character Maidenhead locator
+// to the center of its square.
+func ParseMaidenhead(s string) (Point, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !maidenheadRe.MatchString(s) {
+		return Point{}, errors.New("invalid maidenhead locator: " + s)
+	}
+
+	lng := float64(s[0]-'A')*20 - 180
+	lat := float64(s[1]-'A')*10 - 90
+	lngSize, latSize := 20.0, 10.0
+
+	lngSize, latSize = lngSize/10, latSize/10
+	lng += float64(s[2]-'0') * lngSize
+	lat += float64(s[3]-'0') * latSize
+
+	if len(s) >= 6 {
+		lngSize, latSize = lngSize/24, latSize/24
+		lng += float64(s[4]-'A') * lngSize
+		lat += float64(s[5]-'A') * latSize
+	}
+	if len(s) == 8 {
+		lngSize, latSize = lngSize/10, latSize/10
+		lng += float64(s[6]-'0') * lngSize
+		lat += float64(s[7]-'0') * latSize
+	}
+	return Point{Latitude: lat + latSize/2, Longitude: lng + lngSize/2}, nil
+}
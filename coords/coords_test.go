@@ -0,0 +1,62 @@
+package coords
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	zurich := &Point{Latitude: 47.4, Longitude: 8.6}
+	for _, tc := range []struct {
+		in     string
+		ref    *Point
+		want   Point
+		format string
+	}{
+		{"49.8717,8.6503", nil, Point{49.8717, 8.6503}, Decimal},
+		{"+49.87,+8.65", nil, Point{49.87, 8.65}, Decimal},
+		{"-33.9, -70.6", nil, Point{-33.9, -70.6}, Decimal},
+		{"49.87; 8.65", nil, Point{49.87, 8.65}, Decimal},
+		{"49.87 8.65", nil, Point{49.87, 8.65}, Decimal},
+		{`49°52'18"N 8°39'1"E`, nil, Point{49.871667, 8.650278}, DMS},
+		{"N 49 52.3 E 8 39.0", nil, Point{49.871667, 8.65}, DMS},
+		{"33°54'S 70°36'W", nil, Point{-33.9, -70.6}, DMS},
+		{"gh:u0yjjd6", nil, Point{50.110703, 8.682632}, Geohash},
+		{"GEOHASH:u0yjjd6", nil, Point{50.110703, 8.682632}, Geohash},
+		{"8FVC9G8F+6X", nil, Point{47.365563, 8.524938}, PlusCode},
+		{"8fvc9g8f+6x", nil, Point{47.365563, 8.524938}, PlusCode},
+		{"9G8F+6X", zurich, Point{47.365563, 8.524938}, PlusCode},
+		{"mh:JN49fu", nil, Point{49.854167, 8.458333}, Maidenhead},
+		{"maidenhead:jn49", nil, Point{49.5, 9}, Maidenhead},
+	} {
+		p, format, err := Parse(tc.in, tc.ref)
+		if err != nil {
+			t.Errorf("%q: %v", tc.in, err)
+			continue
+		}
+		if format != tc.format {
+			t.Errorf("%q: format %s, want %s", tc.in, format, tc.format)
+		}
+		if math.Abs(p.Latitude-tc.want.Latitude) > 1e-6 || math.Abs(p.Longitude-tc.want.Longitude) > 1e-6 {
+			t.Errorf("%q: got %.6f, %.6f, want %.6f, %.6f", tc.in, p.Latitude, p.Longitude, tc.want.Latitude, tc.want.Longitude)
+		}
+	}
+}
+
+func TestParseUnknownFormat(t *testing.T) {
+	// place names and postcodes go to the geocoder
+	for _, in := range []string{"Darmstadt", "AB12", "EH12AB", "JN49fu", "u0yjjd6", "Rock+Roll Hall", "C++ Street", ""} {
+		if _, _, err := Parse(in, nil); err != ErrUnknownFormat {
+			t.Errorf("%q: got error %v, want ErrUnknownFormat", in, err)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	// recognized formats with bad values are errors, not place names
+	for _, in := range []string{"91,0", "0,181", "gh:u0yja!", "mh:ZZ99", "9G8F+6X", "8FVC9G8+6X"} {
+		if _, _, err := Parse(in, nil); err == nil || err == ErrUnknownFormat {
+			t.Errorf("%q: got error %v, want a parse error", in, err)
+		}
+	}
+}
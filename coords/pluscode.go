@@ -0,0 +1,139 @@
+package coords
+
+import (
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Open Location Code constants, see https://github.com/google/open-location-code
+const (
+	olcAlphabet     = "23456789CFGHJMPQRVWX"
+	olcSeparator    = '+'
+	olcSeparatorPos = 8
+	olcPadding      = '0'
+	olcPairLength   = 10
+	olcGridRows     = 5
+	olcGridCols     = 4
+)
+
+// plusCodeRe matches the characters and separator layout of full, padded and
+// short plus codes. Other input with a "+" is not a plus code.
+var plusCodeRe = regexp.MustCompile(`^(?i)[23456789CFGHJMPQRVWX0]{2,8}\+[23456789CFGHJMPQRVWX]*$`)
+
+// olcPairResolutions are the degrees covered by a digit in each pair position.
+var olcPairResolutions = []float64{20.0, 1.0, 0.05, 0.0025, 0.000125}
+
+// ParsePlusCode decodes an Open Location Code (plus code) to the center of
+// its area. Short codes like "VMCX+V5" are recovered to the full code nearest
+// to ref, which must not be nil for them.
+func ParsePlusCode(s string, ref *Point) (Point, error) {
+	code := strings.ToUpper(strings.TrimSpace(s))
+	sep := strings.IndexRune(code, olcSeparator)
+	if sep < 0 || sep > olcSeparatorPos || sep%2 != 0 || strings.Count(code, string(olcSeparator)) != 1 {
+		return Point{}, errors.New("invalid plus code: " + s)
+	}
+
+	if sep < olcSeparatorPos {
+		if ref == nil {
+			return Point{}, errors.New("short plus code needs a reference location: " + s)
+		}
+		return recoverPlusCode(code, sep, *ref)
+	}
+	return decodePlusCode(code)
+}
+
+// decodePlusCode decodes a full plus code.
+func decodePlusCode(code string) (Point, error) {
+	digits := strings.Replace(code, string(olcSeparator), "", 1)
+	// padded codes like 8FWC0000+ describe large areas
+	if i := strings.IndexRune(digits, olcPadding); i >= 0 {
+		if strings.Trim(digits[i:], string(olcPadding)) != "" || i%2 != 0 {
+			return Point{}, errors.New("invalid plus code padding: " + code)
+		}
+		digits = digits[:i]
+	}
+	if len(digits) < 2 {
+		return Point{}, errors.New("invalid plus code: " + code)
+	}
+
+	lat, lng := -90.0, -180.0
+	latRes, lngRes := 0.0, 0.0
+	for i := 0; i < len(digits) && i < olcPairLength; i += 2 {
+		latDigit := strings.IndexByte(olcAlphabet, digits[i])
+		lngDigit := -1
+		if i+1 < len(digits) {
+			lngDigit = strings.IndexByte(olcAlphabet, digits[i+1])
+		}
+		if latDigit < 0 || lngDigit < 0 {
+			return Point{}, errors.New("invalid plus code: " + code)
+		}
+		latRes, lngRes = olcPairResolutions[i/2], olcPairResolutions[i/2]
+		lat += float64(latDigit) * latRes
+		lng += float64(lngDigit) * lngRes
+	}
+	for i := olcPairLength; i < len(digits); i++ {
+		d := strings.IndexByte(olcAlphabet, digits[i])
+		if d < 0 {
+			return Point{}, errors.New("invalid plus code: " + code)
+		}
+		latRes /= olcGridRows
+		lngRes /= olcGridCols
+		lat += float64(d/olcGridCols) * latRes
+		lng += float64(d%olcGridCols) * lngRes
+	}
+
+	p := Point{Latitude: lat + latRes/2, Longitude: lng + lngRes/2}
+	return p, p.valid()
+}
+
+// recoverPlusCode completes a short code with the leading digits of ref and
+// moves the result by one cell if that is closer to ref.
+func recoverPlusCode(code string, sep int, ref Point) (Point, error) {
+	padding := olcSeparatorPos - sep
+	resolution := math.Pow(20, float64(2-padding/2))
+	half := resolution / 2
+
+	p, err := decodePlusCode(encodePairs(ref, padding) + code)
+	if err != nil {
+		return p, err
+	}
+
+	if ref.Latitude+half < p.Latitude && p.Latitude-resolution >= -90 {
+		p.Latitude -= resolution
+	} else if ref.Latitude-half > p.Latitude && p.Latitude+resolution <= 90 {
+		p.Latitude += resolution
+	}
+	if ref.Longitude+half < p.Longitude {
+		p.Longitude -= resolution
+	} else if ref.Longitude-half > p.Longitude {
+		p.Longitude += resolution
+	}
+	if p.Longitude < -180 {
+		p.Longitude += 360
+	} else if p.Longitude > 180 {
+		p.Longitude -= 360
+	}
+	return p, nil
+}
+
+// encodePairs returns the first n pair digits of the plus code for p.
+func encodePairs(p Point, n int) string {
+	lat := math.Min(math.Max(p.Latitude, -90), 90) + 90
+	lng := math.Mod(p.Longitude+180, 360)
+	if lng < 0 {
+		lng += 360
+	}
+
+	out := make([]byte, 0, n)
+	for i := 0; i < n; i += 2 {
+		res := olcPairResolutions[i/2]
+		latDigit := int(math.Min(math.Floor(lat/res), 19))
+		lngDigit := int(math.Min(math.Floor(lng/res), 19))
+		lat -= float64(latDigit) * res
+		lng -= float64(lngDigit) * res
+		out = append(out, olcAlphabet[latDigit], olcAlphabet[lngDigit])
+	}
+	return string(out)
+}
@@ -6,12 +6,15 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 )
 
 //various constant values...
 const (
-	//QueryURL format: https://api.forecast.io/forecast/APIKEY/LATITUDE,LONGITUDE[,TIME]?parameters..
-	QueryURL = "https://api.darksky.net/forecast/%s/%.5f,%.5f?units=%s&lang=%s"
+	//DefaultBaseURL is the Dark Sky endpoint used when no other base url is configured
+	DefaultBaseURL = "https://api.darksky.net"
+	//QueryURL format: BASEURL/forecast/APIKEY/LATITUDE,LONGITUDE[,TIME]?parameters..
+	QueryURL = "%s/forecast/%s/%.5f,%.5f?units=%s&lang=%s"
 
 	CA   string = "ca"
 	SI   string = "si"
@@ -20,6 +23,20 @@ const (
 	AUTO string = "auto"
 )
 
+//Languages lists the language codes supported for summaries
+var Languages = []string{
+	"ar", "az", "be", "bg", "bn", "bs", "ca", "cs", "da", "de", "el", "en", "eo", "es", "et",
+	"fi", "fr", "he", "hi", "hr", "hu", "id", "is", "it", "ja", "ka", "kn", "ko", "kw", "lv",
+	"ml", "mr", "nb", "nl", "no", "pa", "pl", "pt", "ro", "ru", "sk", "sl", "sr", "sv", "ta",
+	"te", "tet", "tr", "uk", "ur", "x-pig-latin", "zh", "zh-tw",
+}
+
+//Providers maps the names of known Dark Sky compatible apis to their base urls
+var Providers = map[string]string{
+	"darksky":       DefaultBaseURL,
+	"pirateweather": "https://api.pirateweather.net",
+}
+
 //DataPoint represents the various weather phenomena occurring at a specific instant of time
 type DataPoint struct {
 	Time        int64  `json:"time"`
@@ -60,6 +77,7 @@ type DataPoint struct {
 	ApparentTemperature float64 `json:"apparentTemperature,omitempty"`
 	DewPoint            float64 `json:"dewPoint"`
 	WindSpeed           float64 `json:"windSpeed"`
+	WindGust            float64 `json:"windGust,omitempty"`
 	WindBearing         float64 `json:"windBearing,omitempty"`
 
 	//0 corresponds to clear sky, 0.4 to scattered clouds, 0.75 to broken cloud cover, and 1 to completely overcast skies.
@@ -112,12 +130,17 @@ type Forecast struct {
 	Flags     Flags     `json:"flags"`
 }
 
-//GetForecast queries the forecast.io server and returns a Forecast object
-func GetForecast(key string, lat, lng float64, unitType, lang string) (*Forecast, error) {
+//GetForecast queries the forecast.io compatible server at baseURL and returns a Forecast object.
+//An empty baseURL queries DefaultBaseURL.
+func GetForecast(baseURL, key string, lat, lng float64, unitType, lang string) (*Forecast, error) {
 	fc := &Forecast{}
 
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
 	//build query url
-	url := fmt.Sprintf(QueryURL, key, lat, lng, unitType, lang)
+	url := fmt.Sprintf(QueryURL, strings.TrimRight(baseURL, "/"), key, lat, lng, unitType, lang)
 	//	fmt.Println(url)
 
 	//http get forecast
@@ -131,6 +154,9 @@ func GetForecast(key string, lat, lng float64, unitType, lang string) (*Forecast
 	if err != nil {
 		return nil, errors.New("Problem handling forecast.io data: " + err.Error())
 	}
+	if response.StatusCode != http.StatusOK {
+		return nil, errors.New("forecast.io API responded with " + response.Status + ": " + strings.TrimSpace(string(body)))
+	}
 
 	//decode json response
 	if err = json.Unmarshal(body, fc); err != nil {
@@ -0,0 +1,33 @@
+package geocode
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// nearbyRadius is the distance in kilometers within which a position is
+// described by the place name alone.
+const nearbyRadius = 1.0
+
+// Describe names the position lat, lng relative to the place r,
+// e.g. "4 km NE of Darmstadt" or just "Darmstadt" if it is close by.
+func Describe(lat, lng float64, r Result) string {
+	name := firstNonEmpty(r.Locality, r.Name)
+	dist := utils.Distance(r.Latitude, r.Longitude, lat, lng)
+	if dist < nearbyRadius {
+		return name
+	}
+	dir := utils.CompassPoint(utils.Bearing(r.Latitude, r.Longitude, lat, lng))
+	return fmt.Sprintf("%.0f km %s of %s", math.Round(dist), dir, name)
+}
+
+// ReverseName looks up the place nearest to lat, lng and describes the position relative to it.
+func ReverseName(r Reverser, lat, lng float64) (string, error) {
+	results, err := r.Reverse(lat, lng)
+	if err != nil {
+		return "", err
+	}
+	return Describe(lat, lng, results[0]), nil
+}
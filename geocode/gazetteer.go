@@ -0,0 +1,284 @@
+package geocode
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// GeoNames is the name of the offline geocoder backed by a GeoNames cities dump.
+const GeoNames = "geonames"
+
+// Place is a populated place read from a GeoNames dump.
+type Place struct {
+	Name       string
+	Country    string // ISO-3166 country code
+	Admin1     string // code of the first level administrative region
+	Latitude   float64
+	Longitude  float64
+	Population int64
+}
+
+// nameRef maps a normalized name to the place it belongs to.
+type nameRef struct {
+	key   string
+	place int
+}
+
+// Gazetteer is an offline geocoder for the cities files (cities500, cities1000,
+// cities15000 ..) from https://download.geonames.org/export/dump/. All names
+// including the alternate ones are kept in a sorted index for prefix search.
+type Gazetteer struct {
+	places []Place
+	index  []nameRef
+}
+
+// SearchOptions restricts and tunes a gazetteer search.
+type SearchOptions struct {
+	Countries []string // only return places in these countries (ISO codes)
+	Limit     int      // maximum number of results, 10 if zero
+	Fuzzy     bool     // fall back to fuzzy name matching if nothing matches
+}
+
+// LoadGazetteer reads a GeoNames cities file. Zip archives as offered
+// for download are read directly.
+func LoadGazetteer(path string) (*Gazetteer, error) {
+	if strings.HasSuffix(strings.ToLower(path), ".zip") {
+		zr, err := zip.OpenReader(path)
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			if strings.HasSuffix(f.Name, ".txt") {
+				r, err := f.Open()
+				if err != nil {
+					return nil, err
+				}
+				defer r.Close()
+				return ReadGazetteer(r)
+			}
+		}
+		return nil, errors.New("No GeoNames data found in: " + path)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGazetteer(f)
+}
+
+// ReadGazetteer reads tab separated GeoNames records from r.
+func ReadGazetteer(r io.Reader) (*Gazetteer, error) {
+	g := &Gazetteer{}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	line := 0
+	for scanner.Scan() {
+		line++
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) < 15 {
+			continue
+		}
+		lat, err := strconv.ParseFloat(fields[4], 64)
+		if err != nil {
+			return nil, errors.New("Invalid latitude in GeoNames line " + strconv.Itoa(line))
+		}
+		lng, err := strconv.ParseFloat(fields[5], 64)
+		if err != nil {
+			return nil, errors.New("Invalid longitude in GeoNames line " + strconv.Itoa(line))
+		}
+		pop, _ := strconv.ParseInt(fields[14], 10, 64)
+
+		idx := len(g.places)
+		g.places = append(g.places, Place{
+			Name:       fields[1],
+			Country:    fields[8],
+			Admin1:     fields[10],
+			Latitude:   lat,
+			Longitude:  lng,
+			Population: pop,
+		})
+
+		seen := map[string]bool{}
+		names := append([]string{fields[1], fields[2]}, strings.Split(fields[3], ",")...)
+		for _, n := range names {
+			key := normalize(n)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+			g.index = append(g.index, nameRef{key: key, place: idx})
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(g.index, func(i, j int) bool {
+		return g.index[i].key < g.index[j].key
+	})
+	return g, nil
+}
+
+// Len returns the number of places in the gazetteer.
+func (g *Gazetteer) Len() int {
+	return len(g.places)
+}
+
+// Geocode implements Geocoder. A trailing two letter country code
+// like in "Frankfurt, DE" restricts the search to that country.
+func (g *Gazetteer) Geocode(query string) ([]Result, error) {
+	opts := SearchOptions{Fuzzy: true}
+	if i := strings.LastIndex(query, ","); i >= 0 {
+		if cc := strings.TrimSpace(query[i+1:]); len(cc) == 2 {
+			opts.Countries = []string{cc}
+			query = query[:i]
+		}
+	}
+	return g.Search(query, opts)
+}
+
+// match is a candidate place with the quality of its name match.
+type match struct {
+	place   int
+	quality float64
+}
+
+// Search finds places whose names equal or start with query. Exact matches
+// rank above prefix and fuzzy matches, and within each of them bigger places
+// rank above smaller ones.
+func (g *Gazetteer) Search(query string, opts SearchOptions) ([]Result, error) {
+	key := normalize(query)
+	if key == "" {
+		return nil, ErrNoResults
+	}
+	if opts.Limit <= 0 {
+		opts.Limit = 10
+	}
+
+	best := map[int]float64{}
+	add := func(place int, quality float64) {
+		if !g.inCountries(place, opts.Countries) {
+			return
+		}
+		if quality > best[place] {
+			best[place] = quality
+		}
+	}
+
+	// exact and prefix matches are neighbours in the sorted index
+	for i := sort.Search(len(g.index), func(i int) bool { return g.index[i].key >= key }); i < len(g.index) && strings.HasPrefix(g.index[i].key, key); i++ {
+		if g.index[i].key == key {
+			add(g.index[i].place, 1.0)
+		} else {
+			add(g.index[i].place, 0.6)
+		}
+	}
+
+	if len(best) == 0 && opts.Fuzzy {
+		maxDist := 1
+		if len(key) > 5 {
+			maxDist = 2
+		}
+		for _, ref := range g.index {
+			if d := len(ref.key) - len(key); d > maxDist || d < -maxDist {
+				continue
+			}
+			if d := utils.Levenshtein(ref.key, key); d <= maxDist {
+				add(ref.place, 0.5/float64(d+1))
+			}
+		}
+	}
+
+	if len(best) == 0 {
+		return nil, ErrNoResults
+	}
+
+	matches := make([]match, 0, len(best))
+	for p, q := range best {
+		matches = append(matches, match{place: p, quality: q})
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].quality != matches[j].quality {
+			return matches[i].quality > matches[j].quality
+		}
+		pi, pj := g.places[matches[i].place].Population, g.places[matches[j].place].Population
+		if pi != pj {
+			return pi > pj
+		}
+		return matches[i].place < matches[j].place
+	})
+	if len(matches) > opts.Limit {
+		matches = matches[:opts.Limit]
+	}
+
+	results := make([]Result, len(matches))
+	for i, m := range matches {
+		results[i] = g.result(m.place, m.quality)
+	}
+	return results, nil
+}
+
+// Reverse implements Reverser by returning the nearest place.
+func (g *Gazetteer) Reverse(lat, lng float64) ([]Result, error) {
+	return g.Nearest(lat, lng, 0)
+}
+
+// Nearest returns the place closest to lat, lng with at least minPopulation inhabitants.
+func (g *Gazetteer) Nearest(lat, lng float64, minPopulation int64) ([]Result, error) {
+	best, bestDist := -1, math.Inf(1)
+	for i, p := range g.places {
+		if p.Population < minPopulation {
+			continue
+		}
+		if d := utils.Distance(lat, lng, p.Latitude, p.Longitude); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	if best < 0 {
+		return nil, ErrNoResults
+	}
+	return []Result{g.result(best, 1)}, nil
+}
+
+func (g *Gazetteer) result(place int, confidence float64) Result {
+	p := g.places[place]
+	return Result{
+		Name:       joinNonEmpty(p.Name, p.Country),
+		Locality:   p.Name,
+		Country:    p.Country,
+		Region:     p.Admin1,
+		Latitude:   p.Latitude,
+		Longitude:  p.Longitude,
+		Confidence: confidence,
+	}
+}
+
+func (g *Gazetteer) inCountries(place int, countries []string) bool {
+	if len(countries) == 0 {
+		return true
+	}
+	for _, c := range countries {
+		if strings.EqualFold(c, g.places[place].Country) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize lower cases name and collapses white space.
+func normalize(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,140 @@
+// Package geocode turns place names into coordinates using one of several
+// geocoding services.
+package geocode
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// names of the supported geocoding backends
+const (
+	Google    = "google"
+	Nominatim = "nominatim"
+	Photon    = "photon"
+
+	// Default is used when no geocoder is configured. It needs no api key.
+	Default = Nominatim
+
+	userAgent = "sunlens (https://github.com/dbriemann/sunlens)"
+
+	// DefaultTimeout limits a single request to a geocoding service.
+	DefaultTimeout = 10 * time.Second
+)
+
+// ErrNoResults is returned if a query did not match any place.
+var ErrNoResults = errors.New("zero results")
+
+// Result is a single place found by a geocoder.
+type Result struct {
+	Name       string // human readable address
+	Locality   string // name of the city, town or village
+	Country    string // country name
+	Region     string // first level administrative region, e.g. a state
+	Latitude   float64
+	Longitude  float64
+	Confidence float64 // how well the result matches the query, from 0 to 1
+}
+
+// Geocoder finds places by name. Results are ordered best first.
+type Geocoder interface {
+	Geocode(query string) ([]Result, error)
+}
+
+// String formats r with address, country and coordinates.
+func (r Result) String() string {
+	s := r.Name
+	if r.Country != "" && !strings.Contains(r.Name, r.Country) {
+		s += " (" + r.Country + ")"
+	}
+	return fmt.Sprintf("%s  %.5f, %.5f", s, r.Latitude, r.Longitude)
+}
+
+// Reverser finds the places at or near a position. Results are ordered best first.
+type Reverser interface {
+	Reverse(lat, lng float64) ([]Result, error)
+}
+
+// Options configures a geocoder backend.
+type Options struct {
+	ApiKey   string       // api key, only needed by google
+	BaseURL  string       // endpoint of the service, the public one if empty
+	Language string       // preferred language of the results
+	Client   *http.Client // a client with DefaultTimeout if nil
+	Path     string       // data file of offline geocoders
+}
+
+// New creates the geocoder backend with the given name.
+func New(name string, opts Options) (Geocoder, error) {
+	if opts.Client == nil {
+		opts.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+	switch strings.ToLower(name) {
+	case Google:
+		return &GoogleGeocoder{opts: opts}, nil
+	case Nominatim, "":
+		return &NominatimGeocoder{opts: opts}, nil
+	case Photon:
+		return &PhotonGeocoder{opts: opts}, nil
+	case GeoNames:
+		if opts.Path == "" {
+			return nil, errors.New("The geonames geocoder needs the path to a GeoNames cities file")
+		}
+		return LoadGazetteer(opts.Path)
+	}
+	return nil, errors.New("Unknown geocoder: " + name)
+}
+
+// getJSON requests url and decodes the json response into v.
+func getJSON(client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected response status: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// baseURL returns the configured endpoint or def.
+func (o Options) baseURL(def string) string {
+	if o.BaseURL != "" {
+		return o.BaseURL
+	}
+	return def
+}
+
+// joinNonEmpty joins all non empty and not repeated parts with ", ".
+func joinNonEmpty(parts ...string) string {
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if p == "" || (len(out) > 0 && out[len(out)-1] == p) {
+			continue
+		}
+		out = append(out, p)
+	}
+	return strings.Join(out, ", ")
+}
+
+// firstNonEmpty returns the first of parts that is not empty.
+func firstNonEmpty(parts ...string) string {
+	for _, p := range parts {
+		if p != "" {
+			return p
+		}
+	}
+	return ""
+}
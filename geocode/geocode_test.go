@@ -0,0 +1,51 @@
+package geocode
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+// stubServer answers every request with status and body and records the last request.
+func stubServer(t *testing.T, status int, body string) (*httptest.Server, *url.URL) {
+	t.Helper()
+	last := &url.URL{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*last = *r.URL
+		if ua := r.Header.Get("User-Agent"); ua != userAgent {
+			t.Errorf("User-Agent = %q, want %q", ua, userAgent)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, last
+}
+
+func TestBackendsRejectNon200Responses(t *testing.T) {
+	for _, name := range []string{Google, Nominatim, Photon} {
+		srv, _ := stubServer(t, http.StatusServiceUnavailable, `{}`)
+		g, err := New(name, Options{BaseURL: srv.URL})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := g.Geocode("Darmstadt"); err == nil {
+			t.Errorf("%s: no error for status 503", name)
+		}
+		if _, err := g.(Reverser).Reverse(49.87, 8.65); err == nil {
+			t.Errorf("%s: no error for status 503 on reverse", name)
+		}
+	}
+}
+
+func TestNewUsesClientWithTimeout(t *testing.T) {
+	g, err := New(Nominatim, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := g.(*NominatimGeocoder).opts.Client; c == nil || c.Timeout != DefaultTimeout {
+		t.Errorf("client without DefaultTimeout: %+v", c)
+	}
+}
@@ -0,0 +1,106 @@
+package geocode
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/dbriemann/geopard"
+)
+
+// GoogleURL is the endpoint of the Google geocoding api.
+const GoogleURL = "https://maps.googleapis.com/maps/api/geocode/json"
+
+// GoogleGeocoder queries the Google geocoding api. It requires an api key.
+type GoogleGeocoder struct {
+	opts Options
+}
+
+// Geocode implements Geocoder.
+func (g *GoogleGeocoder) Geocode(query string) ([]Result, error) {
+	params := url.Values{}
+	params.Set("address", query)
+	return g.request(params)
+}
+
+// Reverse implements Reverser.
+func (g *GoogleGeocoder) Reverse(lat, lng float64) ([]Result, error) {
+	params := url.Values{}
+	params.Set("latlng", strconv.FormatFloat(lat, 'f', 6, 64)+","+strconv.FormatFloat(lng, 'f', 6, 64))
+	params.Set("result_type", "locality|sublocality|postal_town|administrative_area_level_3")
+	return g.request(params)
+}
+
+func (g *GoogleGeocoder) request(params url.Values) ([]Result, error) {
+	if g.opts.Language != "" {
+		params.Set("language", g.opts.Language)
+	}
+	params.Set("key", g.opts.ApiKey)
+
+	r := geopard.GResponse{}
+	if err := getJSON(g.opts.Client, g.opts.baseURL(GoogleURL)+"?"+params.Encode(), &r); err != nil {
+		return nil, err
+	}
+
+	switch r.Status {
+	case "OK":
+	case "ZERO_RESULTS":
+		return nil, ErrNoResults
+	case "OVER_QUERY_LIMIT":
+		return nil, geopard.ErrOverLimit
+	case "REQUEST_DENIED":
+		return nil, geopard.ErrRequestDenied
+	case "INVALID_REQUEST":
+		return nil, geopard.ErrInvalidRequest
+	default:
+		return nil, geopard.ErrUnknown
+	}
+
+	results := make([]Result, 0, len(r.Results))
+	for _, gr := range r.Results {
+		res := Result{
+			Name:       gr.FormattedAddr,
+			Latitude:   gr.Geometry.Location.Lat,
+			Longitude:  gr.Geometry.Location.Lng,
+			Confidence: googleConfidence(gr),
+		}
+		for _, c := range gr.AddrComponents {
+			for _, t := range c.Types {
+				switch t {
+				case "country":
+					res.Country = c.Long
+				case "administrative_area_level_1":
+					res.Region = c.Long
+				case "locality", "postal_town":
+					if res.Locality == "" {
+						res.Locality = c.Long
+					}
+				}
+			}
+		}
+		results = append(results, res)
+	}
+	if len(results) == 0 {
+		return nil, ErrNoResults
+	}
+	return results, nil
+}
+
+// googleConfidence derives a confidence value from the precision of the
+// result geometry, as google does not report a score.
+func googleConfidence(r geopard.GResult) float64 {
+	conf := 0.5
+	switch r.Geometry.LocationType {
+	case "ROOFTOP":
+		conf = 1.0
+	case "RANGE_INTERPOLATED":
+		conf = 0.9
+	case "GEOMETRIC_CENTER":
+		conf = 0.8
+	case "APPROXIMATE":
+		conf = 0.7
+	}
+	if r.PartialMatch {
+		conf /= 2
+	}
+	return conf
+}
@@ -0,0 +1,84 @@
+package geocode
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/dbriemann/geopard"
+)
+
+const googleDarmstadt = `{
+  "status": "OK",
+  "results": [{
+    "formatted_address": "Darmstadt, Germany",
+    "geometry": {"location": {"lat": 49.8728, "lng": 8.6512}, "location_type": "APPROXIMATE"},
+    "partial_match": true,
+    "address_components": [
+      {"long_name": "Darmstadt", "short_name": "DA", "types": ["locality", "political"]},
+      {"long_name": "Hessen", "short_name": "HE", "types": ["administrative_area_level_1", "political"]},
+      {"long_name": "Germany", "short_name": "DE", "types": ["country", "political"]}
+    ]
+  }]
+}`
+
+func TestGoogleGeocode(t *testing.T) {
+	srv, last := stubServer(t, http.StatusOK, googleDarmstadt)
+	g := &GoogleGeocoder{opts: Options{BaseURL: srv.URL, ApiKey: "secret", Language: "de", Client: http.DefaultClient}}
+
+	results, err := g.Geocode("Darmstadt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := last.Query()
+	if q.Get("address") != "Darmstadt" || q.Get("key") != "secret" || q.Get("language") != "de" {
+		t.Errorf("unexpected query: %s", last.RawQuery)
+	}
+
+	want := Result{
+		Name:       "Darmstadt, Germany",
+		Locality:   "Darmstadt",
+		Country:    "Germany",
+		Region:     "Hessen",
+		Latitude:   49.8728,
+		Longitude:  8.6512,
+		Confidence: 0.35, // approximate and only a partial match
+	}
+	if len(results) != 1 || results[0] != want {
+		t.Errorf("got %+v, want %+v", results, want)
+	}
+}
+
+func TestGoogleReverse(t *testing.T) {
+	srv, last := stubServer(t, http.StatusOK, googleDarmstadt)
+	g := &GoogleGeocoder{opts: Options{BaseURL: srv.URL, Client: http.DefaultClient}}
+
+	results, err := g.Reverse(49.87, 8.65)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := last.Query().Get("latlng"); got != "49.870000,8.650000" {
+		t.Errorf("latlng = %q", got)
+	}
+	if results[0].Locality != "Darmstadt" {
+		t.Errorf("got %+v", results[0])
+	}
+}
+
+func TestGoogleStatus(t *testing.T) {
+	for _, tc := range []struct {
+		status string
+		err    error
+	}{
+		{"ZERO_RESULTS", ErrNoResults},
+		{"REQUEST_DENIED", geopard.ErrRequestDenied},
+		{"OVER_QUERY_LIMIT", geopard.ErrOverLimit},
+		{"INVALID_REQUEST", geopard.ErrInvalidRequest},
+		{"SOMETHING_NEW", geopard.ErrUnknown},
+	} {
+		srv, _ := stubServer(t, http.StatusOK, `{"status": "`+tc.status+`", "results": []}`)
+		g := &GoogleGeocoder{opts: Options{BaseURL: srv.URL, Client: http.DefaultClient}}
+		if _, err := g.Geocode("x"); err != tc.err {
+			t.Errorf("%s: got error %v, want %v", tc.status, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package geocode
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// NominatimURL is the public OpenStreetMap Nominatim endpoint.
+// See https://operations.osmfoundation.org/policies/nominatim/ for its usage policy.
+const NominatimURL = "https://nominatim.openstreetmap.org"
+
+// NominatimGeocoder queries an OpenStreetMap Nominatim server.
+type NominatimGeocoder struct {
+	opts Options
+}
+
+type nominatimPlace struct {
+	Lat         string  `json:"lat"`
+	Lon         string  `json:"lon"`
+	DisplayName string  `json:"display_name"`
+	Importance  float64 `json:"importance"`
+	Address     struct {
+		City    string `json:"city"`
+		Town    string `json:"town"`
+		Village string `json:"village"`
+		Hamlet  string `json:"hamlet"`
+		Country string `json:"country"`
+		State   string `json:"state"`
+		Region  string `json:"region"`
+	} `json:"address"`
+}
+
+// Geocode implements Geocoder.
+func (n *NominatimGeocoder) Geocode(query string) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("limit", "10")
+	return n.request("/search", params)
+}
+
+// Reverse implements Reverser. Results are on the level of towns, not streets.
+func (n *NominatimGeocoder) Reverse(lat, lng float64) ([]Result, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(lat, 'f', 6, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', 6, 64))
+	params.Set("zoom", "13")
+
+	// reverse lookups answer with a single place instead of a list
+	place := nominatimPlace{}
+	if err := n.get("/reverse", params, &place); err != nil {
+		return nil, err
+	}
+	return n.results([]nominatimPlace{place})
+}
+
+func (n *NominatimGeocoder) request(endpoint string, params url.Values) ([]Result, error) {
+	places := []nominatimPlace{}
+	if err := n.get(endpoint, params, &places); err != nil {
+		return nil, err
+	}
+	return n.results(places)
+}
+
+func (n *NominatimGeocoder) get(endpoint string, params url.Values, v interface{}) error {
+	params.Set("format", "jsonv2")
+	params.Set("addressdetails", "1")
+	if n.opts.Language != "" {
+		params.Set("accept-language", n.opts.Language)
+	}
+	return getJSON(n.opts.Client, n.opts.baseURL(NominatimURL)+endpoint+"?"+params.Encode(), v)
+}
+
+func (n *NominatimGeocoder) results(places []nominatimPlace) ([]Result, error) {
+	results := make([]Result, 0, len(places))
+	for _, p := range places {
+		lat, err := strconv.ParseFloat(p.Lat, 64)
+		if err != nil {
+			continue
+		}
+		lng, err := strconv.ParseFloat(p.Lon, 64)
+		if err != nil {
+			continue
+		}
+		region := p.Address.State
+		if region == "" {
+			region = p.Address.Region
+		}
+		results = append(results, Result{
+			Name:       p.DisplayName,
+			Locality:   firstNonEmpty(p.Address.City, p.Address.Town, p.Address.Village, p.Address.Hamlet),
+			Country:    p.Address.Country,
+			Region:     region,
+			Latitude:   lat,
+			Longitude:  lng,
+			Confidence: p.Importance,
+		})
+	}
+	if len(results) == 0 {
+		return nil, ErrNoResults
+	}
+	return results, nil
+}
@@ -0,0 +1,64 @@
+package geocode
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestNominatimGeocode(t *testing.T) {
+	srv, last := stubServer(t, http.StatusOK, `[
+	  {"lat": "49.8728", "lon": "8.6512", "display_name": "Darmstadt, Hessen, Deutschland", "importance": 0.72,
+	   "address": {"city": "Darmstadt", "state": "Hessen", "country": "Deutschland"}},
+	  {"lat": "bad", "lon": "8.0", "display_name": "skipped"},
+	  {"lat": "50.1", "lon": "9.2", "display_name": "Darmstadt, Bayern", "importance": 0.3,
+	   "address": {"village": "Darmstadt", "region": "Unterfranken", "country": "Deutschland"}}
+	]`)
+	n := &NominatimGeocoder{opts: Options{BaseURL: srv.URL, Language: "de", Client: http.DefaultClient}}
+
+	results, err := n.Geocode("Darmstadt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := last.Query()
+	if last.Path != "/search" || q.Get("q") != "Darmstadt" || q.Get("format") != "jsonv2" || q.Get("accept-language") != "de" {
+		t.Errorf("unexpected request: %s", last)
+	}
+
+	want := []Result{
+		{Name: "Darmstadt, Hessen, Deutschland", Locality: "Darmstadt", Country: "Deutschland", Region: "Hessen", Latitude: 49.8728, Longitude: 8.6512, Confidence: 0.72},
+		{Name: "Darmstadt, Bayern", Locality: "Darmstadt", Country: "Deutschland", Region: "Unterfranken", Latitude: 50.1, Longitude: 9.2, Confidence: 0.3},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d: got %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestNominatimNoResults(t *testing.T) {
+	srv, _ := stubServer(t, http.StatusOK, `[]`)
+	n := &NominatimGeocoder{opts: Options{BaseURL: srv.URL, Client: http.DefaultClient}}
+	if _, err := n.Geocode("nowhere"); err != ErrNoResults {
+		t.Errorf("got error %v, want %v", err, ErrNoResults)
+	}
+}
+
+func TestNominatimReverse(t *testing.T) {
+	srv, last := stubServer(t, http.StatusOK, `{"lat": "49.87", "lon": "8.65", "display_name": "Bessungen, Darmstadt",
+	  "address": {"town": "Darmstadt", "country": "Deutschland"}}`)
+	n := &NominatimGeocoder{opts: Options{BaseURL: srv.URL, Client: http.DefaultClient}}
+
+	results, err := n.Reverse(49.87, 8.65)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Path != "/reverse" || last.Query().Get("lat") != "49.870000" || last.Query().Get("lon") != "8.650000" {
+		t.Errorf("unexpected request: %s", last)
+	}
+	if len(results) != 1 || results[0].Locality != "Darmstadt" || results[0].Country != "Deutschland" {
+		t.Errorf("got %+v", results)
+	}
+}
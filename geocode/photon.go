@@ -0,0 +1,81 @@
+package geocode
+
+import (
+	"net/url"
+	"strconv"
+)
+
+// PhotonURL is the public Photon endpoint run by komoot.
+const PhotonURL = "https://photon.komoot.io"
+
+// PhotonGeocoder queries a Photon server, a search-as-you-type geocoder for OpenStreetMap data.
+type PhotonGeocoder struct {
+	opts Options
+}
+
+type photonResponse struct {
+	Features []struct {
+		Geometry struct {
+			Coordinates []float64 `json:"coordinates"` // longitude, latitude
+		} `json:"geometry"`
+		Properties struct {
+			Name    string `json:"name"`
+			Street  string `json:"street"`
+			City    string `json:"city"`
+			State   string `json:"state"`
+			Country string `json:"country"`
+		} `json:"properties"`
+	} `json:"features"`
+}
+
+// Geocode implements Geocoder.
+func (p *PhotonGeocoder) Geocode(query string) ([]Result, error) {
+	params := url.Values{}
+	params.Set("q", query)
+	params.Set("limit", "10")
+	return p.request("/api/", params)
+}
+
+// Reverse implements Reverser.
+func (p *PhotonGeocoder) Reverse(lat, lng float64) ([]Result, error) {
+	params := url.Values{}
+	params.Set("lat", strconv.FormatFloat(lat, 'f', 6, 64))
+	params.Set("lon", strconv.FormatFloat(lng, 'f', 6, 64))
+	params.Set("osm_tag", "place")
+	return p.request("/reverse", params)
+}
+
+func (p *PhotonGeocoder) request(endpoint string, params url.Values) ([]Result, error) {
+	// photon only supports a few languages and rejects all others
+	switch p.opts.Language {
+	case "en", "de", "fr", "it":
+		params.Set("lang", p.opts.Language)
+	}
+
+	r := photonResponse{}
+	if err := getJSON(p.opts.Client, p.opts.baseURL(PhotonURL)+endpoint+"?"+params.Encode(), &r); err != nil {
+		return nil, err
+	}
+
+	results := make([]Result, 0, len(r.Features))
+	for i, f := range r.Features {
+		if len(f.Geometry.Coordinates) < 2 {
+			continue
+		}
+		props := f.Properties
+		results = append(results, Result{
+			Name:      joinNonEmpty(props.Name, props.Street, props.City, props.State, props.Country),
+			Locality:  firstNonEmpty(props.City, props.Name),
+			Country:   props.Country,
+			Region:    props.State,
+			Latitude:  f.Geometry.Coordinates[1],
+			Longitude: f.Geometry.Coordinates[0],
+			// photon reports no score but orders results by relevance
+			Confidence: 1 / float64(i+1),
+		})
+	}
+	if len(results) == 0 {
+		return nil, ErrNoResults
+	}
+	return results, nil
+}
@@ -0,0 +1,68 @@
+package geocode
+
+import (
+	"net/http"
+	"testing"
+)
+
+const photonDarmstadt = `{"features": [
+  {"geometry": {"coordinates": [8.6512, 49.8728]},
+   "properties": {"name": "Darmstadt", "state": "Hessen", "country": "Germany"}},
+  {"geometry": {"coordinates": []}, "properties": {"name": "skipped"}},
+  {"geometry": {"coordinates": [8.66, 49.87]},
+   "properties": {"name": "Luisenplatz", "street": "Rheinstraße", "city": "Darmstadt", "state": "Hessen", "country": "Germany"}}
+]}`
+
+func TestPhotonGeocode(t *testing.T) {
+	srv, last := stubServer(t, http.StatusOK, photonDarmstadt)
+	p := &PhotonGeocoder{opts: Options{BaseURL: srv.URL, Language: "ru", Client: http.DefaultClient}}
+
+	results, err := p.Geocode("Darmstadt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last.Path != "/api/" || last.Query().Get("q") != "Darmstadt" {
+		t.Errorf("unexpected request: %s", last)
+	}
+	if _, ok := last.Query()["lang"]; ok {
+		t.Errorf("unsupported language was sent: %s", last.RawQuery)
+	}
+
+	want := []Result{
+		{Name: "Darmstadt, Hessen, Germany", Locality: "Darmstadt", Country: "Germany", Region: "Hessen", Latitude: 49.8728, Longitude: 8.6512, Confidence: 1},
+		{Name: "Luisenplatz, Rheinstraße, Darmstadt, Hessen, Germany", Locality: "Darmstadt", Country: "Germany", Region: "Hessen", Latitude: 49.87, Longitude: 8.66, Confidence: 1.0 / 3},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i := range want {
+		if results[i] != want[i] {
+			t.Errorf("result %d: got %+v, want %+v", i, results[i], want[i])
+		}
+	}
+}
+
+func TestPhotonReverse(t *testing.T) {
+	srv, last := stubServer(t, http.StatusOK, photonDarmstadt)
+	p := &PhotonGeocoder{opts: Options{BaseURL: srv.URL, Language: "de", Client: http.DefaultClient}}
+
+	results, err := p.Reverse(49.87, 8.65)
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := last.Query()
+	if last.Path != "/reverse" || q.Get("lat") != "49.870000" || q.Get("lon") != "8.650000" || q.Get("lang") != "de" {
+		t.Errorf("unexpected request: %s", last)
+	}
+	if results[0].Locality != "Darmstadt" {
+		t.Errorf("got %+v", results[0])
+	}
+}
+
+func TestPhotonNoResults(t *testing.T) {
+	srv, _ := stubServer(t, http.StatusOK, `{"features": []}`)
+	p := &PhotonGeocoder{opts: Options{BaseURL: srv.URL, Client: http.DefaultClient}}
+	if _, err := p.Geocode("nowhere"); err != ErrNoResults {
+		t.Errorf("got error %v, want %v", err, ErrNoResults)
+	}
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dbriemann/sunlens/config"
+)
+
+const locUsage = `Usage: sunlens loc <command> [arguments]
+
+Commands:
+  list                        list all saved locations and groups
+  add [--first] <shortcut> [query..]
+                              geocode query (default: shortcut) and save it
+  remove <shortcut>           delete a saved location
+  rename <shortcut> <new>     change the shortcut of a saved location
+  default <shortcut>          use a saved location when none is given
+  show <shortcut|@group>      show the details of a saved location or group
+  group <name> [shortcut..]   set the members of a group, none deletes it
+  import [--format f] [--conflicts abort|skip|replace] <file>
+                              add the locations of a GeoJSON, GPX or CSV file
+  export [--format f] [--only shortcut,@group,..] [file]
+                              write locations to a file or stdout (default: geojson)
+
+Shortcuts may be given with or without the leading "#". The file "-" is stdin or stdout.
+CSV files have the columns name, lat, lon, shortcut.`
+
+// runLoc manages the saved locations in the config file.
+func runLoc(args []string) {
+	if len(args) == 0 {
+		fmt.Println(locUsage)
+		os.Exit(0)
+	}
+
+	var err error
+	switch cmd, args := args[0], args[1:]; cmd {
+	case "list":
+		err = locList()
+	case "show":
+		if len(args) != 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		if config.IsGroup(args[0]) {
+			err = groupShow(args[0])
+			break
+		}
+		err = locShow(config.Shortcut(args[0]))
+	case "add":
+		flags := flag.NewFlagSet("add", flag.ExitOnError)
+		first := flags.Bool("first", false, "use the best match if the query is ambiguous")
+		flags.Parse(args)
+		if args = flags.Args(); len(args) < 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = locAdd(config.Shortcut(args[0]), strings.Join(args[1:], " "), chooser(*first))
+	case "remove":
+		if len(args) != 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = config.Update(configFile(), func(c *config.Config) error {
+			return c.RemoveLocation(config.Shortcut(args[0]))
+		})
+	case "rename":
+		if len(args) != 2 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = config.Update(configFile(), func(c *config.Config) error {
+			return c.RenameLocation(config.Shortcut(args[0]), config.Shortcut(args[1]))
+		})
+	case "default":
+		if len(args) != 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = config.Update(configFile(), func(c *config.Config) error {
+			return c.SetDefaultLocation(config.Shortcut(args[0]))
+		})
+	case "group":
+		if len(args) < 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = config.Update(configFile(), func(c *config.Config) error {
+			return c.SetGroup(args[0], args[1:])
+		})
+	case "import":
+		flags := flag.NewFlagSet("import", flag.ExitOnError)
+		format := flags.String("format", "", "file format: geojson, gpx or csv (default: from the file extension)")
+		conflicts := flags.String("conflicts", config.ConflictAbort, "on conflicts with saved locations: abort, skip or replace")
+		flags.Parse(args)
+		if flags.NArg() != 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = locImport(flags.Arg(0), *format, *conflicts)
+	case "export":
+		flags := flag.NewFlagSet("export", flag.ExitOnError)
+		format := flags.String("format", "", "file format: geojson, gpx or csv (default: from the file extension)")
+		only := flags.String("only", "", "comma separated shortcuts and groups to export (default: all)")
+		flags.Parse(args)
+		if flags.NArg() > 1 {
+			err = errors.New(locUsage)
+			break
+		}
+		err = locExport(flags.Arg(0), *format, *only)
+	default:
+		err = errors.New("Unknown command: " + cmd + "\n\n" + locUsage)
+	}
+
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(0)
+	}
+}
+
+func locList() error {
+	conf, err := config.LoadConfig(configFile(), config.Location{})
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for i, l := range conf.Locations {
+		marker := " "
+		if i == conf.DefaultLocation {
+			marker = "*"
+		}
+		fmt.Fprintf(w, "%s %s\t%s\t%.5f, %.5f\n", marker, l.Shortcut, l.City, l.Latitude, l.Longitude)
+	}
+	for _, name := range conf.GroupNames() {
+		fmt.Fprintf(w, "  %s%s\t%s\n", config.GroupPrefix, name, strings.Join(conf.Groups[name], " "))
+	}
+	return w.Flush()
+}
+
+func groupShow(name string) error {
+	conf, err := config.LoadConfig(configFile(), config.Location{})
+	if err != nil {
+		return err
+	}
+	locs, err := conf.Group(name)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, l := range locs {
+		fmt.Fprintf(w, "%s\t%s\t%.5f, %.5f\n", l.Shortcut, l.City, l.Latitude, l.Longitude)
+	}
+	return w.Flush()
+}
+
+func locShow(shortcut string) error {
+	conf, err := config.LoadConfig(configFile(), config.Location{})
+	if err != nil {
+		return err
+	}
+	i := conf.FindLocation(shortcut)
+	if i < 0 {
+		return errors.New("Unknown location: " + shortcut)
+	}
+
+	l := conf.Locations[i]
+	fmt.Printf("Shortcut:  %s\n", l.Shortcut)
+	fmt.Printf("City:      %s\n", l.City)
+	fmt.Printf("Latitude:  %.5f\n", l.Latitude)
+	fmt.Printf("Longitude: %.5f\n", l.Longitude)
+	fmt.Printf("Default:   %t\n", i == conf.DefaultLocation)
+	return nil
+}
+
+func locAdd(shortcut, query string, choose config.Chooser) error {
+	if query == "" {
+		query = strings.TrimPrefix(shortcut, "#")
+	}
+	conf, err := config.LoadConfig(configFile(), config.Location{})
+	if err != nil {
+		return err
+	}
+	geocoder, err := conf.NewGeocoder()
+	if err != nil {
+		return err
+	}
+	loc, err := config.NewLocation(geocoder, query, choose)
+	if err != nil {
+		return err
+	}
+	loc.Shortcut = shortcut
+
+	return config.Update(configFile(), func(c *config.Config) error {
+		if c.FindLocation(shortcut) >= 0 {
+			return errors.New("Location already exists: " + shortcut)
+		}
+		stored, added := c.UpsertLocation(loc)
+		if !added {
+			return errors.New("Location is already saved nearby as: " + stored.Shortcut + " (" + stored.City + ")")
+		}
+		fmt.Printf("Saved new location: %s [shortcut:%s]\n", stored.City, stored.Shortcut)
+		return nil
+	})
+}
+
+// exchangeFormat returns format or the one matching the extension of file.
+func exchangeFormat(file, format string) (string, error) {
+	if format != "" {
+		return format, nil
+	}
+	if file == "" || file == "-" {
+		return config.FormatGeoJSON, nil
+	}
+	return config.FormatOf(file)
+}
+
+func locImport(file, format, policy string) error {
+	format, err := exchangeFormat(file, format)
+	if err != nil {
+		return err
+	}
+	var r io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	locs, err := config.ReadLocations(r, format)
+	if err != nil {
+		return err
+	}
+
+	return config.Update(configFile(), func(c *config.Config) error {
+		res, err := c.ImportLocations(locs, policy)
+		if err != nil {
+			return err
+		}
+		for _, l := range res.Added {
+			fmt.Printf("Added:    %s (%s)\n", l.Shortcut, l.City)
+		}
+		for _, l := range res.Replaced {
+			fmt.Printf("Replaced: %s (%s)\n", l.Shortcut, l.City)
+		}
+		for _, cf := range res.Conflicts {
+			fmt.Println("Skipped:  " + cf.String())
+		}
+		fmt.Printf("%d added, %d replaced, %d unchanged, %d skipped\n", len(res.Added), len(res.Replaced), len(res.Unchanged), len(res.Conflicts))
+		return nil
+	})
+}
+
+func locExport(file, format, only string) error {
+	format, err := exchangeFormat(file, format)
+	if err != nil {
+		return err
+	}
+	conf, err := config.LoadConfig(configFile(), config.Location{})
+	if err != nil {
+		return err
+	}
+	locs := conf.Locations
+	if only != "" {
+		if locs, err = conf.Expand(strings.Split(only, ",")); err != nil {
+			return err
+		}
+	}
+
+	if file == "" || file == "-" {
+		return config.WriteLocations(os.Stdout, format, locs)
+	}
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if err := config.WriteLocations(f, format, locs); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d location(s) to %s\n", len(locs), file)
+	return nil
+}
@@ -0,0 +1,56 @@
+package locate
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"net"
+	"time"
+)
+
+// gpsdWatch asks gpsd to stream reports as json.
+const gpsdWatch = `?WATCH={"enable":true,"json":true};` + "\n"
+
+// gpsdReport is the part of a gpsd json report sunlens needs.
+// See https://gpsd.gitlab.io/gpsd/gpsd_json.html
+type gpsdReport struct {
+	Class string   `json:"class"`
+	Mode  int      `json:"mode"` // 0: unknown, 1: no fix, 2: 2D fix, 3: 3D fix
+	Lat   *float64 `json:"lat"`
+	Lon   *float64 `json:"lon"`
+}
+
+// GPSD connects to a gpsd daemon at addr and waits up to timeout
+// for a time-position-velocity report with at least a 2D fix.
+func GPSD(addr string, timeout time.Duration) (Fix, error) {
+	deadline := time.Now().Add(timeout)
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return Fix{}, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte(gpsdWatch)); err != nil {
+		return Fix{}, err
+	}
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		r := gpsdReport{}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		if r.Class != "TPV" || r.Mode < 2 || r.Lat == nil || r.Lon == nil {
+			continue
+		}
+		return Fix{Latitude: *r.Lat, Longitude: *r.Lon, Source: SourceGPS, Time: time.Now()}, nil
+	}
+	if err := scanner.Err(); err != nil {
+		if ne, ok := err.(net.Error); ok && ne.Timeout() {
+			return Fix{}, errors.New("no position fix within " + timeout.String())
+		}
+		return Fix{}, err
+	}
+	return Fix{}, errors.New("connection closed without position fix")
+}
@@ -0,0 +1,81 @@
+package locate
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeGPSD serves a single client with lines after it sent the watch
+// command. The connection is closed after the lines if hangUp is set and
+// kept open until the test ends otherwise.
+func fakeGPSD(t *testing.T, lines []string, hangUp bool) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		close(done)
+		ln.Close()
+	})
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		watch, err := bufio.NewReader(conn).ReadString('\n')
+		if err != nil || watch != gpsdWatch {
+			t.Errorf("unexpected watch command %q", watch)
+			return
+		}
+		for _, l := range lines {
+			conn.Write([]byte(l + "\n"))
+		}
+		if !hangUp {
+			<-done
+		}
+	}()
+	return ln.Addr().String()
+}
+
+const (
+	gpsdVersion = `{"class":"VERSION","release":"3.22","rev":"3.22","proto_major":3,"proto_minor":14}`
+	gpsdNoFix   = `{"class":"TPV","device":"/dev/ttyACM0","mode":1}`
+	gpsdFix     = `{"class":"TPV","device":"/dev/ttyACM0","mode":3,"lat":49.8717,"lon":8.6503,"alt":150.2}`
+)
+
+func TestGPSD(t *testing.T) {
+	addr := fakeGPSD(t, []string{
+		gpsdVersion,
+		`{"class":"DEVICES","devices":[{"path":"/dev/ttyACM0"}]}`,
+		gpsdNoFix,
+		`{"class":"SKY","satellites":[]}`,
+		gpsdFix,
+	}, false)
+
+	fix, err := GPSD(addr, time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fix.Latitude != 49.8717 || fix.Longitude != 8.6503 || fix.Source != SourceGPS {
+		t.Errorf("got %+v", fix)
+	}
+}
+
+func TestGPSDWithoutFix(t *testing.T) {
+	addr := fakeGPSD(t, []string{gpsdVersion, gpsdNoFix}, false)
+	if _, err := GPSD(addr, 200*time.Millisecond); err == nil || !strings.Contains(err.Error(), "no position fix") {
+		t.Errorf("got error %v, want a timeout", err)
+	}
+
+	addr = fakeGPSD(t, []string{gpsdVersion, gpsdNoFix}, true)
+	if _, err := GPSD(addr, time.Second); err == nil || !strings.Contains(err.Error(), "closed") {
+		t.Errorf("got error %v, want a closed connection", err)
+	}
+}
@@ -0,0 +1,184 @@
+// Package locate detects the current position of the machine, either from a
+// gpsd daemon or by ip geolocation.
+package locate
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// default settings
+const (
+	DefaultGPSD     = "localhost:2947"
+	DefaultIPGeoURL = "https://ipapi.co/json/"
+	DefaultTimeout  = 3 * time.Second
+	// Off disables a position source when used as its address.
+	Off = "off"
+)
+
+// sources of a position fix
+const (
+	SourceGPS = "gps"
+	SourceIP  = "ip"
+)
+
+// Fix is a detected position.
+type Fix struct {
+	Latitude  float64
+	Longitude float64
+	City      string // only known for ip geolocation
+	Source    string
+	Time      time.Time
+}
+
+// Locator finds the current position by asking gpsd first and an ip
+// geolocation service second. Results are cached in CacheFile for CacheTime.
+type Locator struct {
+	GPSD      string        // address of gpsd, DefaultGPSD if empty, Off to disable
+	IPGeoURL  string        // ip geolocation endpoint, DefaultIPGeoURL if empty, Off to disable
+	Timeout   time.Duration // per source, DefaultTimeout if zero
+	CacheFile string        // no caching if empty
+	CacheTime time.Duration // no caching if zero
+	Client    *http.Client  // a client with Timeout if nil
+}
+
+// Locate returns the current position.
+func (l *Locator) Locate() (Fix, error) {
+	if fix, ok := l.cached(); ok {
+		return fix, nil
+	}
+
+	timeout := l.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+
+	var errs []string
+	if l.GPSD != Off {
+		addr := l.GPSD
+		if addr == "" {
+			addr = DefaultGPSD
+		}
+		fix, err := GPSD(addr, timeout)
+		if err == nil {
+			l.store(fix)
+			return fix, nil
+		}
+		errs = append(errs, "gpsd: "+err.Error())
+	}
+
+	if l.IPGeoURL != Off {
+		u := l.IPGeoURL
+		if u == "" {
+			u = DefaultIPGeoURL
+		}
+		client := l.Client
+		if client == nil {
+			client = &http.Client{Timeout: timeout}
+		}
+		fix, err := IPGeolocation(client, u)
+		if err == nil {
+			l.store(fix)
+			return fix, nil
+		}
+		errs = append(errs, "ip geolocation: "+err.Error())
+	}
+
+	if len(errs) == 0 {
+		return Fix{}, errors.New("All position sources are disabled")
+	}
+	return Fix{}, errors.New("Could not detect current position: " + strings.Join(errs, ", "))
+}
+
+func (l *Locator) cached() (Fix, bool) {
+	if l.CacheFile == "" || l.CacheTime <= 0 {
+		return Fix{}, false
+	}
+	b, err := ioutil.ReadFile(l.CacheFile)
+	if err != nil {
+		return Fix{}, false
+	}
+	fix := Fix{}
+	if err := json.Unmarshal(b, &fix); err != nil || time.Since(fix.Time) > l.CacheTime {
+		return Fix{}, false
+	}
+	return fix, true
+}
+
+// store caches fix. Failing to write the cache is not fatal.
+func (l *Locator) store(fix Fix) {
+	if l.CacheFile == "" || l.CacheTime <= 0 {
+		return
+	}
+	if b, err := json.Marshal(fix); err == nil {
+		ioutil.WriteFile(l.CacheFile, b, 0600)
+	}
+}
+
+// ipGeoResponse covers the field names of common ip geolocation services
+// like ipapi.co, ip-api.com, freegeoip and ipinfo.io.
+type ipGeoResponse struct {
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	Lat       *float64 `json:"lat"`
+	Lon       *float64 `json:"lon"`
+	Loc       string   `json:"loc"` // "lat,lng"
+	City      string   `json:"city"`
+	Error     bool     `json:"error"`
+	Reason    string   `json:"reason"`
+	Status    string   `json:"status"`
+	Message   string   `json:"message"`
+}
+
+// IPGeolocation asks the service at url for the position of this machine's public ip.
+func IPGeolocation(client *http.Client, url string) (Fix, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return Fix{}, err
+	}
+	req.Header.Set("User-Agent", "sunlens (https://github.com/dbriemann/sunlens)")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Fix{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Fix{}, errors.New("unexpected response status: " + resp.Status)
+	}
+
+	r := ipGeoResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
+		return Fix{}, err
+	}
+	if r.Error || r.Status == "fail" {
+		return Fix{}, errors.New(r.Reason + r.Message)
+	}
+
+	fix := Fix{City: r.City, Source: SourceIP, Time: time.Now()}
+	switch {
+	case r.Latitude != nil && r.Longitude != nil:
+		fix.Latitude, fix.Longitude = *r.Latitude, *r.Longitude
+	case r.Lat != nil && r.Lon != nil:
+		fix.Latitude, fix.Longitude = *r.Lat, *r.Lon
+	case r.Loc != "":
+		parts := strings.Split(r.Loc, ",")
+		if len(parts) != 2 {
+			return Fix{}, errors.New("invalid position: " + r.Loc)
+		}
+		if fix.Latitude, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+			return Fix{}, err
+		}
+		if fix.Longitude, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+			return Fix{}, err
+		}
+	default:
+		return Fix{}, errors.New("no position in response")
+	}
+	return fix, nil
+}
@@ -0,0 +1,129 @@
+package locate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// ipGeoServer answers with body and counts the requests.
+func ipGeoServer(t *testing.T, body string) (string, *int32) {
+	t.Helper()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, &calls
+}
+
+// closedAddr returns an address nothing listens on.
+func closedAddr(t *testing.T) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	return addr
+}
+
+func TestLocatePrefersGPSD(t *testing.T) {
+	url, calls := ipGeoServer(t, `{"latitude": 1, "longitude": 2}`)
+	l := &Locator{GPSD: fakeGPSD(t, []string{gpsdVersion, gpsdFix}, false), IPGeoURL: url, Timeout: time.Second}
+
+	fix, err := l.Locate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fix.Source != SourceGPS || atomic.LoadInt32(calls) != 0 {
+		t.Errorf("got %+v after %d ip requests", fix, atomic.LoadInt32(calls))
+	}
+}
+
+func TestLocateFallsBackToIPGeolocation(t *testing.T) {
+	url, _ := ipGeoServer(t, `{"ip": "192.0.2.1", "city": "Darmstadt", "latitude": 49.87, "longitude": 8.65}`)
+	l := &Locator{GPSD: closedAddr(t), IPGeoURL: url, Timeout: time.Second}
+
+	fix, err := l.Locate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fix.Source != SourceIP || fix.City != "Darmstadt" || fix.Latitude != 49.87 || fix.Longitude != 8.65 {
+		t.Errorf("got %+v", fix)
+	}
+}
+
+func TestLocateAllSourcesFail(t *testing.T) {
+	l := &Locator{GPSD: Off, IPGeoURL: Off}
+	if _, err := l.Locate(); err == nil {
+		t.Error("no error with all sources disabled")
+	}
+
+	url, _ := ipGeoServer(t, `{"error": true, "reason": "RateLimited"}`)
+	l = &Locator{GPSD: closedAddr(t), IPGeoURL: url, Timeout: time.Second}
+	if _, err := l.Locate(); err == nil {
+		t.Error("no error with failing sources")
+	}
+}
+
+func TestIPGeolocationFormats(t *testing.T) {
+	for _, body := range []string{
+		`{"latitude": 49.87, "longitude": 8.65, "city": "Darmstadt"}`,           // ipapi.co
+		`{"status": "success", "lat": 49.87, "lon": 8.65, "city": "Darmstadt"}`, // ip-api.com
+		`{"loc": "49.8700,8.6500", "city": "Darmstadt"}`,                        // ipinfo.io
+	} {
+		url, _ := ipGeoServer(t, body)
+		fix, err := IPGeolocation(http.DefaultClient, url)
+		if err != nil {
+			t.Errorf("%s: %v", body, err)
+			continue
+		}
+		if fix.Latitude != 49.87 || fix.Longitude != 8.65 || fix.City != "Darmstadt" {
+			t.Errorf("%s: got %+v", body, fix)
+		}
+	}
+
+	for _, body := range []string{`{"status": "fail", "message": "private range"}`, `{"city": "Nowhere"}`, `{"loc": "49.87"}`} {
+		url, _ := ipGeoServer(t, body)
+		if _, err := IPGeolocation(http.DefaultClient, url); err == nil {
+			t.Errorf("%s: no error", body)
+		}
+	}
+}
+
+func TestLocateCache(t *testing.T) {
+	url, calls := ipGeoServer(t, `{"latitude": 49.87, "longitude": 8.65}`)
+	cache := filepath.Join(t.TempDir(), "here.cache")
+	l := &Locator{GPSD: Off, IPGeoURL: url, CacheFile: cache, CacheTime: time.Minute}
+
+	for i := 0; i < 3; i++ {
+		if _, err := l.Locate(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := atomic.LoadInt32(calls); got != 1 {
+		t.Errorf("%d requests within CacheTime, want 1", got)
+	}
+
+	// an expired fix is detected again
+	old, _ := json.Marshal(Fix{Latitude: 1, Longitude: 2, Source: SourceIP, Time: time.Now().Add(-2 * time.Minute)})
+	if err := ioutil.WriteFile(cache, old, 0600); err != nil {
+		t.Fatal(err)
+	}
+	fix, err := l.Locate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := atomic.LoadInt32(calls); got != 2 || fix.Latitude != 49.87 {
+		t.Errorf("got %+v after %d requests, want a new fix", fix, got)
+	}
+}
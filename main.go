@@ -1,109 +1,213 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"os"
-	"os/user"
-	"path"
+	"path/filepath"
 
 	"fmt"
 
-	"github.com/dbriemann/geopard"
 	"github.com/dbriemann/sunlens/config"
 	"github.com/dbriemann/sunlens/forecastio"
 	"github.com/dbriemann/sunlens/terminal"
 )
 
 const (
-	configExtPath  = ".config/sunlens/"
 	configFileName = "sunlens.cfg"
+	// geoCacheFileName stores geocoding results for places that are not saved
+	geoCacheFileName = "geocode.cache"
+	// hereCacheFileName stores the last detected position of "@here"
+	hereCacheFileName = "here.cache"
 )
 
 var (
-	usrHome string
+	configPath string // set by --config, SUNLENS_CONFIG or the config directory
 )
 
-func init() {
-	usr, err := user.Current()
+// configFile returns the path to the users config file.
+func configFile() string {
+	return configPath
+}
+
+// setConfigPath finds the config file and creates its directory if it does not exist yet.
+func setConfigPath(flagValue string) error {
+	configPath = flagValue
+	if configPath == "" {
+		dir, err := config.ConfigDir()
+		if err != nil {
+			return err
+		}
+		configPath = filepath.Join(dir, configFileName)
+	}
+	dir := filepath.Dir(configPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return errors.New("Unable to create config directory: " + dir + " Error: " + err.Error())
+	}
+	return nil
+}
+
+// cacheFile returns the path of the cache file name. Caches used to live next
+// to the config file and are moved over. An empty path disables the cache.
+func cacheFile(name string) string {
+	dir, err := config.CacheDir()
 	if err != nil {
-		fmt.Println("Could not detect user: " + err.Error())
+		return ""
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ""
+	}
+	file := filepath.Join(dir, name)
+	if _, err := os.Stat(file); os.IsNotExist(err) {
+		os.Rename(filepath.Join(filepath.Dir(configPath), name), file)
+	}
+	return file
+}
+
+func main() {
+	first := flag.Bool("first", false, "use the best match if a place name is ambiguous")
+	profile := flag.String("profile", os.Getenv(config.ProfileEnv), "name of the config profile to use")
+	confFlag := flag.String("config", os.Getenv(config.ConfigEnv), "path of the config file")
+	width := flag.Int("width", 0, "output width in columns (default: terminal width, $COLUMNS or 80)")
+	height := flag.Int("height", 0, "output height in lines (default: terminal height, $LINES or 24)")
+	flag.Parse()
+	args := flag.Args()
+
+	if err := setConfigPath(*confFlag); err != nil {
+		fmt.Println(err.Error())
 		os.Exit(0)
-	} else {
-		usrHome = usr.HomeDir
 	}
 
-	//create config path directory if it does not exist yet
-	basePath := path.Join(usr.HomeDir, configExtPath)
-	if err := os.MkdirAll(basePath, 0755); err != nil {
-		fmt.Println("Unable to create config directory: " + basePath + " Error: " + err.Error())
+	//dispatch sub commands
+	if len(args) > 0 {
+		switch args[0] {
+		case "proxy":
+			runProxy(args[1:], *profile)
+			return
+		case "loc":
+			runLoc(args[1:])
+			return
+		case "config":
+			runConfig(args[1:])
+			return
+		}
+	}
+
+	//load config from file or run the setup if none exists yet
+	conf, err := config.LoadConfig(configFile(), config.Location{})
+	if err == config.ErrNoConfig && terminal.IsTerminal(os.Stdin) {
+		//first run: ask for the basic settings instead of failing
+		conf, err = runSetupWizard(configFile())
+	}
+	if err != nil {
+		fmt.Println(err.Error())
 		os.Exit(0)
 	}
-}
+	if *profile != "" {
+		if err := conf.ApplyProfile(*profile); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(0)
+		}
+	}
+	config.SetCurrent(conf)
+
+	//if no location is given use default location from config
+	loc, _ := conf.Default()
+	locs := []config.Location{loc}
+	if len(args) > 0 {
+		//every argument is a location, groups stand for all their members
+		//saved shortcuts and cached places are resolved without network access
+		cache, _ := config.LoadGeoCache(cacheFile(geoCacheFileName))
+		resolver := config.Resolver{
+			Config:      conf,
+			Cache:       cache,
+			NewGeocoder: conf.NewGeocoder,
+			Choose:      chooser(*first),
+			Here:        conf.Here.Locator(cacheFile(hereCacheFileName)),
+		}
 
-func main() {
-	//TODO
-	//1. load or create config without location
-	//2. get argument
-	//3. is shortcut/location/nothing -> load/create/default location
-	//4. x/add/add to config
-	//parse command line arguments
-	var loc config.Location
-	if len(os.Args) > 1 {
-		//take first argument if there is one, ignore all following
-		locArg := os.Args[1]
-
-		location, err := config.NewLocation(locArg)
+		resolved, save, err := resolver.ResolveAll(args)
 		if err != nil {
 			fmt.Println(err.Error())
 			os.Exit(0)
 		}
-		loc = location
+		locs = resolved
+
+		if err := saveLocations(locs, save); err != nil {
+			fmt.Println(err.Error())
+			os.Exit(0)
+		}
 	}
 
-	//load config from file or create default config if none exists yet
-	configPath := path.Join(usrHome, configExtPath, configFileName)
-	conf, err := config.LoadConfig(configPath, loc)
-	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(0)
+	//render at the size of the terminal, plain if piped
+	opts := terminal.DetectOptions(os.Stdout)
+	if *width > 0 {
+		opts.Cols = *width
+	}
+	if *height > 0 {
+		opts.Rows = *height
 	}
-	config.Settings = conf
-
-	//if location is not set use default location from config
-	if loc.Shortcut == "" {
-		loc = conf.Locations[conf.DefaultLocation]
-	} else {
-		location := loc
-		//check if location already exists in config
-		for _, c := range conf.Locations {
-			if loc.Shortcut == c.Shortcut {
-				location = c
+
+	for i, loc := range locs {
+		if i > 0 {
+			fmt.Println()
+		}
+		if err := showForecast(conf, loc, opts); err != nil {
+			fmt.Println(err.Error())
+			if len(locs) == 1 {
+				os.Exit(0)
 			}
 		}
+	}
+}
 
-		//and save a new location in the config file
-		if location.Latitude != loc.Latitude && location.Longitude != loc.Longitude {
-			fmt.Println("Saving new location: ", location)
-			loc = location
+// saveLocations saves the newly geocoded locations in the config file.
+// The locations are replaced by the stored ones.
+func saveLocations(locs []config.Location, save []bool) error {
+	needed := false
+	for _, s := range save {
+		needed = needed || s
+	}
+	if !needed {
+		return nil
+	}
+	return config.Update(configFile(), func(c *config.Config) error {
+		for i := range locs {
+			if !save[i] {
+				continue
+			}
+			stored, added := c.UpsertLocation(locs[i])
+			if added {
+				fmt.Printf("Saving new location: %s [shortcut:%s]\n", stored.City, stored.Shortcut)
+			}
+			locs[i] = stored
 		}
+		return nil
+	})
+}
+
+// showForecast requests and renders the forecast for loc.
+func showForecast(conf *config.Config, loc config.Location, opts terminal.Options) error {
+	//settings of the location override the global ones
+	settings, err := conf.SettingsFor(loc)
+	if err != nil {
+		return err
 	}
 
 	//request forecast data from forecast.io
-	fc, err := forecastio.GetForecast(conf.ApiKey, loc.Latitude, loc.Longitude, conf.UnitFormat, conf.Language)
+	fc, err := forecastio.GetForecast(settings.BaseURL, settings.ApiKey, loc.Latitude, loc.Longitude, settings.UnitFormat, settings.Language)
 	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(0)
+		return err
 	}
 
 	//create terminal to render data in ascii
-	term, err := terminal.NewTerminal(fc)
+	term, err := terminal.NewTerminal(fc, settings, opts)
 	if err != nil {
-		fmt.Println(err.Error())
-		os.Exit(0)
+		return err
 	}
 
 	fmt.Printf(" Weather for: %s [shortcut:%s]\n", loc.City, loc.Shortcut)
 
 	term.Render()
-
-	geopard.GetInstance().Destroy()
+	return nil
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/dbriemann/sunlens/config"
+	"github.com/dbriemann/sunlens/geocode"
+	"github.com/dbriemann/sunlens/terminal"
+)
+
+// chooser returns how ambiguous place names are resolved: with --first the
+// best match is used, on a terminal the user is asked, otherwise it is an error.
+func chooser(first bool) config.Chooser {
+	if first {
+		return config.FirstResult
+	}
+	if terminal.IsTerminal(os.Stdin) {
+		return pickResult
+	}
+	return config.RejectAmbiguous
+}
+
+// pickResult shows a numbered list of places and asks the user to choose one.
+func pickResult(desc string, results []geocode.Result) (int, error) {
+	return pickFrom(bufio.NewReader(os.Stdin))(desc, results)
+}
+
+// pickFrom returns a Chooser like pickResult that reads the answer from in,
+// so input typed ahead in a dialog using the same reader is not lost.
+func pickFrom(in *bufio.Reader) config.Chooser {
+	return func(desc string, results []geocode.Result) (int, error) {
+		fmt.Printf("Multiple places match: %s\n", desc)
+		for i, r := range results {
+			fmt.Printf(" %2d) %s\n", i+1, r)
+		}
+
+		for {
+			fmt.Printf("Choose a place [1-%d, default 1]: ", len(results))
+			line, err := in.ReadString('\n')
+			if err != nil {
+				return 0, errors.New("No place chosen for: " + desc)
+			}
+			line = strings.TrimSpace(line)
+			if line == "" {
+				return 0, nil
+			}
+			if n, err := strconv.Atoi(line); err == nil && n >= 1 && n <= len(results) {
+				return n - 1, nil
+			}
+			fmt.Printf("Please enter a number between 1 and %d.\n", len(results))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/dbriemann/sunlens/config"
+	"github.com/dbriemann/sunlens/forecastio"
+	"github.com/dbriemann/sunlens/proxy"
+)
+
+// runProxy starts a caching forecast proxy so a team can share one api key.
+// Clients point their BaseURL config setting at the proxy. The config is
+// watched with the given profile applied.
+func runProxy(args []string, profile string) {
+	flags := flag.NewFlagSet("proxy", flag.ExitOnError)
+	listen := flags.String("listen", "localhost:8080", "address the proxy listens on")
+	ttl := flags.Duration("ttl", proxy.DefaultTTL, "time a forecast is cached")
+	precision := flags.Int("precision", proxy.DefaultPrecision, "decimals coordinates are rounded to for caching")
+	upstream := flags.String("upstream", "", "upstream api base url (default: the provider or BaseURL of the config, else "+forecastio.DefaultBaseURL+")")
+	flags.Parse(args)
+
+	watcher, err := config.NewWatcher(configFile(), profile)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(0)
+	}
+
+	base, key, err := upstreamOf(config.Current(), *upstream)
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(0)
+	}
+	srv := proxy.NewServer(base, key)
+	srv.TTL = *ttl
+	srv.Precision = *precision
+
+	//pick up a new provider or api key without restarting the proxy
+	watcher.OnChange = func(c *config.Config) {
+		base, key, err := upstreamOf(c, *upstream)
+		if err != nil {
+			fmt.Println(err.Error() + "\nKeeping the previous upstream.")
+			return
+		}
+		srv.SetUpstream(base, key)
+	}
+	go watcher.Run(nil)
+
+	fmt.Printf("Forecast proxy listening on http://%s\n", *listen)
+	if err := http.ListenAndServe(*listen, srv); err != nil {
+		fmt.Println("Proxy stopped: " + err.Error())
+		os.Exit(1)
+	}
+}
+
+// upstreamOf returns the forecast api and key selected by the provider
+// settings of c. The --upstream flag overrides the api.
+func upstreamOf(c *config.Config, flagValue string) (string, string, error) {
+	settings, err := c.SettingsFor(config.Location{})
+	if err != nil {
+		return "", "", err
+	}
+	if flagValue != "" {
+		return flagValue, settings.ApiKey, nil
+	}
+	return settings.BaseURL, settings.ApiKey, nil
+}
@@ -0,0 +1,264 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dbriemann/sunlens/forecastio"
+)
+
+const (
+	// DefaultPrecision rounds coordinates to two decimals (roughly 1km).
+	DefaultPrecision = 2
+	// DefaultTTL is the time a cached forecast is served before it is fetched again.
+	DefaultTTL = 10 * time.Minute
+	// DefaultTimeout limits an upstream request, waiting clients are released with an error after it.
+	DefaultTimeout = 30 * time.Second
+)
+
+// entry is a cached upstream response.
+type entry struct {
+	status      int
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// call is an upstream request in flight that other requests for
+// the same cache key wait for instead of asking upstream again.
+type call struct {
+	wg  sync.WaitGroup
+	res *entry
+	err error
+}
+
+// Server is a caching http proxy speaking the Dark Sky compatible url scheme:
+//
+//	/forecast/APIKEY/LATITUDE,LONGITUDE[,TIME]?parameters..
+//
+// The api key sent by clients is ignored and replaced by the shared ApiKey.
+type Server struct {
+	Upstream  string        // base url of the upstream api, forecastio.DefaultBaseURL if empty, see SetUpstream
+	ApiKey    string        // shared api key used for all upstream requests, see SetUpstream
+	Precision int           // number of decimals coordinates are rounded to
+	TTL       time.Duration // time a response is cached
+	Client    *http.Client  // should have a Timeout, a client with DefaultTimeout if nil
+	Logger    *log.Logger   // logs upstream errors, the standard logger if nil
+
+	mu       sync.Mutex
+	cache    map[string]*entry
+	inflight map[string]*call
+	upstream int // number of upstream requests, for logging
+}
+
+// NewServer creates a proxy server with default cache settings.
+func NewServer(upstream, apiKey string) *Server {
+	return &Server{
+		Upstream:  upstream,
+		ApiKey:    apiKey,
+		Precision: DefaultPrecision,
+		TTL:       DefaultTTL,
+		Client:    &http.Client{Timeout: DefaultTimeout},
+		cache:     make(map[string]*entry),
+		inflight:  make(map[string]*call),
+	}
+}
+
+// ServeHTTP answers forecast requests from the cache or forwards them upstream.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	point, err := s.parsePoint(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	query := canonicalQuery(r.URL.Query())
+	key := point + "?" + query
+
+	res, hit, err := s.get(key, point, query)
+	if err != nil {
+		// the error contains the upstream url and with it the shared api key
+		s.logf("upstream request for %s failed: %s", point, err.Error())
+		http.Error(w, "Problem talking to upstream API", http.StatusBadGateway)
+		return
+	}
+
+	if res.contentType != "" {
+		w.Header().Set("Content-Type", res.contentType)
+	}
+	if hit {
+		w.Header().Set("X-Sunlens-Cache", "HIT")
+	} else {
+		w.Header().Set("X-Sunlens-Cache", "MISS")
+	}
+	w.WriteHeader(res.status)
+	w.Write(res.body)
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.Logger != nil {
+		s.Logger.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// SetUpstream replaces the upstream api and its key, e.g. after the provider
+// in the config changed. Cached responses of the old upstream are dropped.
+func (s *Server) SetUpstream(upstream, key string) {
+	s.mu.Lock()
+	if upstream != s.Upstream {
+		s.cache = make(map[string]*entry)
+	}
+	s.Upstream = upstream
+	s.ApiKey = key
+	s.mu.Unlock()
+}
+
+// UpstreamRequests returns the number of requests forwarded upstream so far.
+func (s *Server) UpstreamRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.upstream
+}
+
+// get returns the cached response for key or fetches it. Concurrent
+// misses for the same key share a single upstream request.
+func (s *Server) get(key, point, query string) (*entry, bool, error) {
+	s.mu.Lock()
+	if e, ok := s.cache[key]; ok {
+		if time.Now().Before(e.expires) {
+			s.mu.Unlock()
+			return e, true, nil
+		}
+		delete(s.cache, key)
+	}
+	if c, ok := s.inflight[key]; ok {
+		s.mu.Unlock()
+		c.wg.Wait()
+		return c.res, false, c.err
+	}
+	c := &call{}
+	c.wg.Add(1)
+	s.inflight[key] = c
+	s.upstream++
+	s.mu.Unlock()
+
+	c.res, c.err = s.fetch(point, query)
+
+	s.mu.Lock()
+	delete(s.inflight, key)
+	if c.err == nil && c.res.status == http.StatusOK {
+		s.sweep()
+		s.cache[key] = c.res
+	}
+	s.mu.Unlock()
+	c.wg.Done()
+
+	return c.res, false, c.err
+}
+
+// sweep removes expired entries. s.mu must be held.
+func (s *Server) sweep() {
+	now := time.Now()
+	for k, e := range s.cache {
+		if now.After(e.expires) {
+			delete(s.cache, k)
+		}
+	}
+}
+
+func (s *Server) fetch(point, query string) (*entry, error) {
+	s.mu.Lock()
+	base, key := s.Upstream, s.ApiKey
+	s.mu.Unlock()
+	if base == "" {
+		base = forecastio.DefaultBaseURL
+	}
+	u := fmt.Sprintf("%s/forecast/%s/%s", strings.TrimRight(base, "/"), url.PathEscape(key), point)
+	if query != "" {
+		u += "?" + query
+	}
+
+	client := s.Client
+	if client == nil {
+		client = &http.Client{Timeout: DefaultTimeout}
+	}
+	response, err := client.Get(u)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry{
+		status:      response.StatusCode,
+		contentType: response.Header.Get("Content-Type"),
+		body:        body,
+		expires:     time.Now().Add(s.TTL),
+	}, nil
+}
+
+// parsePoint extracts the rounded "lat,lng[,time]" part from a forecast url path.
+func (s *Server) parsePoint(path string) (string, error) {
+	parts := strings.Split(strings.Trim(path, "/"), "/")
+	if len(parts) != 3 || parts[0] != "forecast" {
+		return "", errors.New("expected /forecast/APIKEY/LATITUDE,LONGITUDE[,TIME]")
+	}
+
+	fields := strings.Split(parts[2], ",")
+	if len(fields) < 2 || len(fields) > 3 {
+		return "", errors.New("invalid location: " + parts[2])
+	}
+	lat, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil || lat < -90 || lat > 90 {
+		return "", errors.New("invalid latitude: " + fields[0])
+	}
+	lng, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil || lng < -180 || lng > 180 {
+		return "", errors.New("invalid longitude: " + fields[1])
+	}
+
+	point := strconv.FormatFloat(lat, 'f', s.Precision, 64) + "," + strconv.FormatFloat(lng, 'f', s.Precision, 64)
+	if len(fields) == 3 {
+		point += "," + fields[2]
+	}
+	return point, nil
+}
+
+// canonicalQuery encodes the query parameters in a stable order
+// so equal requests map to the same cache key.
+func canonicalQuery(values url.Values) string {
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		vals := values[k]
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, url.QueryEscape(k)+"="+url.QueryEscape(v))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -0,0 +1,134 @@
+package proxy
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConcurrentMissesShareOneUpstreamRequest(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"latitude":52.52,"longitude":13.4}`))
+	}))
+	defer upstream.Close()
+
+	srv := NewServer(upstream.URL, "secret")
+	front := httptest.NewServer(srv)
+	defer front.Close()
+
+	const n = 20
+	var wg sync.WaitGroup
+	statuses := make([]int, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http.Get(front.URL + "/forecast/client-key/52.5201,13.4049?units=si")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			resp.Body.Close()
+			statuses[i] = resp.StatusCode
+		}(i)
+	}
+
+	// give the requests time to pile up behind the first one
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if got := atomic.LoadInt32(&calls); got != 1 {
+		t.Errorf("upstream called %d times, want 1", got)
+	}
+	if got := srv.UpstreamRequests(); got != 1 {
+		t.Errorf("UpstreamRequests() = %d, want 1", got)
+	}
+	for i, status := range statuses {
+		if status != http.StatusOK {
+			t.Errorf("request %d: status %d, want %d", i, status, http.StatusOK)
+		}
+	}
+}
+
+func TestHungUpstreamDoesNotBlockLaterRequests(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer upstream.Close()
+	defer close(release)
+
+	srv := NewServer(upstream.URL, "secret")
+	srv.Client = &http.Client{Timeout: 50 * time.Millisecond}
+
+	get := func() int {
+		rec := httptest.NewRecorder()
+		srv.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/forecast/key/1.0,2.0", nil))
+		return rec.Code
+	}
+
+	if status := get(); status != http.StatusBadGateway {
+		t.Fatalf("hung upstream: status %d, want %d", status, http.StatusBadGateway)
+	}
+
+	done := make(chan int)
+	go func() { done <- get() }()
+	select {
+	case status := <-done:
+		if status != http.StatusOK {
+			t.Errorf("retry: status %d, want %d", status, http.StatusOK)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("retry still waits for the timed out request")
+	}
+	if got := atomic.LoadInt32(&calls); got != 2 {
+		t.Errorf("upstream called %d times, want 2", got)
+	}
+}
+
+func TestUpstreamErrorsDoNotLeakApiKey(t *testing.T) {
+	const key = "SHARED-SECRET-KEY"
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	url := upstream.URL
+	upstream.Close() // nothing listens there any more
+
+	var logged bytes.Buffer
+	srv := NewServer(url, key)
+	srv.Logger = log.New(&logged, "", 0)
+	front := httptest.NewServer(srv)
+	defer front.Close()
+
+	resp, err := http.Get(front.URL + "/forecast/client-key/1.0,2.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusBadGateway {
+		t.Errorf("status %d, want %d", resp.StatusCode, http.StatusBadGateway)
+	}
+	if strings.Contains(string(body), key) {
+		t.Errorf("response body contains the api key: %s", body)
+	}
+	if logged.Len() == 0 {
+		t.Error("upstream error was not logged")
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/dbriemann/sunlens/config"
+	"github.com/dbriemann/sunlens/forecastio"
+	"github.com/dbriemann/sunlens/terminal"
+)
+
+// api keys are tested with a forecast for Greenwich
+const testLatitude, testLongitude = 51.4779, 0.0015
+
+// setupOptions are the answers of the setup wizard or the flags of "config init".
+type setupOptions struct {
+	provider    string // name of a known provider
+	baseURL     string // base url of another Dark Sky compatible api
+	apiKey      string
+	geocoder    string
+	geocoderKey string
+	gazetteer   string
+	location    string
+	home        *config.Location // location already resolved by the wizard
+	shortcut    string
+	units       string
+	language    string
+	skipTest    bool
+}
+
+// configInit creates the config file from flags. Without flags on a
+// terminal it runs the interactive setup instead.
+func configInit(args []string) {
+	flags := flag.NewFlagSet("init", flag.ExitOnError)
+	opts := setupOptions{}
+	flags.StringVar(&opts.provider, "provider", "darksky", "forecast provider: "+strings.Join(providerNames(), ", "))
+	flags.StringVar(&opts.baseURL, "base-url", "", "base url of another Dark Sky compatible api, replaces --provider")
+	flags.StringVar(&opts.apiKey, "api-key", "", "api key of the forecast provider")
+	flags.StringVar(&opts.geocoder, "geocoder", "", "geocoding service: nominatim, photon, google or geonames")
+	flags.StringVar(&opts.geocoderKey, "geocoder-key", "", "api key of the geocoding service")
+	flags.StringVar(&opts.gazetteer, "gazetteer", "", "GeoNames cities file for the geonames geocoder")
+	flags.StringVar(&opts.location, "location", "", "home location: place name or coordinates")
+	flags.StringVar(&opts.shortcut, "shortcut", "", "shortcut of the home location (default: its name)")
+	flags.StringVar(&opts.units, "units", forecastio.AUTO, "unit format: auto, ca, si, uk or us")
+	flags.StringVar(&opts.language, "lang", "en", "language of forecast summaries")
+	flags.BoolVar(&opts.skipTest, "skip-test", false, "do not test the api key")
+	first := flags.Bool("first", false, "use the best match if the location is ambiguous")
+	force := flags.Bool("force", false, "overwrite an existing config file")
+	flags.Parse(args)
+
+	path := configFile()
+	if _, err := os.Stat(path); err == nil && !*force {
+		fmt.Println("Config file already exists: " + path + "\nUse --force to overwrite it.")
+		os.Exit(1)
+	}
+
+	var err error
+	if flags.NFlag() == 0 && terminal.IsTerminal(os.Stdin) {
+		_, err = runSetupWizard(path)
+	} else {
+		var conf *config.Config
+		if conf, err = buildConfig(opts, chooser(*first)); err == nil {
+			err = writeSetup(path, conf)
+		}
+	}
+	if err != nil {
+		fmt.Println(err.Error())
+		os.Exit(1)
+	}
+}
+
+// runSetupWizard asks the user for all settings needed to get started
+// and writes the config file to path.
+func runSetupWizard(path string) (*config.Config, error) {
+	in := bufio.NewReader(os.Stdin)
+	opts := setupOptions{}
+
+	fmt.Println("Welcome to sunlens! Let's create your config file: " + path)
+
+	// forecast provider
+	names := providerNames()
+	fmt.Println("\nForecast providers:")
+	for i, name := range names {
+		fmt.Printf(" %2d) %-14s %s\n", i+1, name, forecastio.Providers[name])
+	}
+	fmt.Printf(" %2d) other Dark Sky compatible api or team proxy\n", len(names)+1)
+	n := askNumber(in, "Choose a provider", 1, len(names)+1)
+	if n <= len(names) {
+		opts.provider = names[n-1]
+	} else {
+		opts.baseURL = ask(in, "Base url", "")
+	}
+
+	// api key, tested until it works or the user gives up
+	// a team proxy uses its own key, so none is needed for it
+	keyPrompt := "Api key"
+	if opts.baseURL != "" {
+		keyPrompt = "Api key (empty for a team proxy)"
+	}
+	for {
+		opts.apiKey = ask(in, keyPrompt, "")
+		if opts.apiKey == "" && opts.baseURL == "" {
+			continue
+		}
+		fmt.Print("Testing api key.. ")
+		if err := testApiKey(opts); err != nil {
+			fmt.Println("failed:\n" + err.Error())
+			if askYesNo(in, "Try another key?", true) {
+				continue
+			}
+		} else {
+			fmt.Println("OK")
+		}
+		break
+	}
+	opts.skipTest = true
+
+	// home location
+	conf := config.DefaultConfig()
+	for {
+		opts.location = ask(in, "\nHome location (place name or coordinates)", "")
+		if opts.location == "" {
+			continue
+		}
+		loc, err := resolveSetupLocation(conf, opts.location, pickFrom(in))
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		fmt.Printf("Found: %s (%.5f, %.5f)\n", loc.City, loc.Latitude, loc.Longitude)
+		opts.shortcut = ask(in, "Shortcut for this location", strings.TrimPrefix(loc.Shortcut, "#"))
+		opts.home = &loc
+		break
+	}
+
+	// units and language
+	for {
+		opts.units = ask(in, "\nUnits (auto, ca, si, uk, us)", forecastio.AUTO)
+		opts.language = ask(in, "Language of forecast summaries", "en")
+
+		c, err := buildConfig(opts, nil)
+		if err != nil {
+			fmt.Println(err.Error())
+			continue
+		}
+		return c, writeSetup(path, c)
+	}
+}
+
+// buildConfig creates a validated config from opts.
+func buildConfig(opts setupOptions, choose config.Chooser) (*config.Config, error) {
+	c := config.DefaultConfig()
+	if opts.baseURL != "" {
+		c.BaseURL = opts.baseURL
+	} else if opts.provider != "" {
+		c.Provider = opts.provider
+	}
+	c.ApiKey = opts.apiKey
+	if opts.geocoder != "" {
+		c.Geocoder = opts.geocoder
+	}
+	c.GeocoderKey = opts.geocoderKey
+	c.GazetteerPath = opts.gazetteer
+	c.UnitFormat = opts.units
+	c.Language = opts.language
+
+	var loc config.Location
+	if opts.home != nil {
+		loc = *opts.home
+	} else if opts.location == "" {
+		return nil, errors.New("Please give a home location with --location")
+	} else {
+		var err error
+		if loc, err = resolveSetupLocation(c, opts.location, choose); err != nil {
+			return nil, err
+		}
+	}
+	if opts.shortcut != "" {
+		loc.Shortcut = config.Shortcut(opts.shortcut)
+	}
+	c.Locations = []config.Location{loc}
+	c.DefaultLocation = 0
+
+	if problems := c.Validate(); len(problems) > 0 {
+		msg := "Invalid settings:"
+		for _, p := range problems {
+			msg += "\n  " + p.String()
+		}
+		return nil, errors.New(msg)
+	}
+
+	if !opts.skipTest {
+		if err := testApiKey(opts); err != nil {
+			return nil, errors.New("Api key test failed: " + err.Error() + "\nUse --skip-test to write the config anyway.")
+		}
+	}
+	return c, nil
+}
+
+// resolveSetupLocation finds the home location with the geocoder of c.
+func resolveSetupLocation(c *config.Config, arg string, choose config.Chooser) (config.Location, error) {
+	geocoder, err := c.NewGeocoder()
+	if err != nil {
+		return config.Location{}, err
+	}
+	resolver := config.Resolver{Geocoder: geocoder, Choose: choose}
+	loc, _, err := resolver.Resolve(arg)
+	return loc, err
+}
+
+// testApiKey requests a forecast to check that the api key works.
+func testApiKey(opts setupOptions) error {
+	baseURL := opts.baseURL
+	if baseURL == "" {
+		baseURL = forecastio.Providers[opts.provider]
+	}
+	_, err := forecastio.GetForecast(baseURL, opts.apiKey, testLatitude, testLongitude, forecastio.AUTO, "en")
+	return err
+}
+
+// writeSetup saves c as the new config file.
+func writeSetup(path string, c *config.Config) error {
+	if err := c.Save(path); err != nil {
+		return errors.New("Could not save config file: " + err.Error())
+	}
+	fmt.Println("Created config file: " + path)
+	return nil
+}
+
+// providerNames returns the names of the known forecast providers, sorted.
+func providerNames() []string {
+	names := make([]string, 0, len(forecastio.Providers))
+	for name := range forecastio.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ask prints question and reads an answer. An empty answer returns def.
+func ask(in *bufio.Reader, question, def string) string {
+	if def != "" {
+		fmt.Printf("%s [%s]: ", question, def)
+	} else {
+		fmt.Printf("%s: ", question)
+	}
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Println()
+		fmt.Println("Setup aborted.")
+		os.Exit(1)
+	}
+	if line = strings.TrimSpace(line); line == "" {
+		return def
+	}
+	return line
+}
+
+// askNumber asks for a number from 1 to max.
+func askNumber(in *bufio.Reader, question string, def, max int) int {
+	for {
+		answer := ask(in, fmt.Sprintf("%s (1-%d)", question, max), strconv.Itoa(def))
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= max {
+			return n
+		}
+		fmt.Printf("Please enter a number between 1 and %d.\n", max)
+	}
+}
+
+// askYesNo asks a yes or no question.
+func askYesNo(in *bufio.Reader, question string, def bool) bool {
+	d := "n"
+	if def {
+		d = "y"
+	}
+	answer := strings.ToLower(ask(in, question+" (y/n)", d))
+	return strings.HasPrefix(answer, "y")
+}
@@ -0,0 +1,91 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/dbriemann/sunlens/ascii"
+	"github.com/dbriemann/sunlens/utils"
+)
+
+const (
+	// precipRows is the height of the precipitation panel
+	precipRows = 3
+	// full scale of the bars: heavy precipitation
+	precipScaleMetric = 10.0 // mm/h
+	precipScaleUS     = 0.4  // in/h
+)
+
+// eighthBlocks are the partial bars from one to seven eighths.
+var eighthBlocks = []rune{' ', '▁', '▂', '▃', '▄', '▅', '▆', '▇', '█'}
+
+// precipColors are the colors of unlikely and certain precipitation
+// by type. The probability blends between them.
+var precipColors = map[string][2]utils.Color{
+	rain:  {{R: 1, G: 1, B: 2}, {R: 0, G: 2, B: 5}},
+	snow:  {{R: 2, G: 2, B: 2}, {R: 5, G: 5, B: 5}},
+	sleet: {{R: 1, G: 2, B: 2}, {R: 2, G: 4, B: 5}},
+	hail:  {{R: 2, G: 1, B: 2}, {R: 5, G: 2, B: 5}},
+}
+
+// precipColor returns the color of a bar for precipitation of type
+// precipType with the given probability.
+func precipColor(precipType string, probability float64) utils.Color {
+	colors, ok := precipColors[precipType]
+	if !ok {
+		colors = precipColors[rain]
+	}
+	lo := utils.HeatColor{Temperature: 0, Color: colors[0]}
+	hi := utils.HeatColor{Temperature: 1, Color: colors[1]}
+	return utils.ColorByInterpolation(&lo, &hi, math.Min(math.Max(probability, 0), 1))
+}
+
+// renderPrecipitation prints the hourly precipitation intensity as bars.
+// Bars fill up at heavy precipitation, any precipitation shows at least
+// one eighth.
+func (t *Terminal) renderPrecipitation() {
+	scale, unit := precipScaleMetric, "mm"
+	if t.forecast.Flags.Units == "us" {
+		scale, unit = precipScaleUS, "in"
+	}
+
+	var hours []hourData
+	for _, day := range t.days {
+		hours = append(hours, day.hourly...)
+	}
+	eighths := make([]int, len(hours))
+	for i, h := range hours {
+		e := int(math.Round(h.precipIntensity / scale * precipRows * 8))
+		if e > precipRows*8 {
+			e = precipRows * 8
+		} else if e == 0 && h.precipIntensity > 0 {
+			e = 1
+		}
+		eighths[i] = e
+	}
+
+	for row := precipRows - 1; row >= 0; row-- {
+		line := strings.Repeat(" ", leftSideBarWidth)
+		if row == precipRows-1 {
+			line = fmt.Sprintf("%-*s", leftSideBarWidth, fmt.Sprintf("%g%s", scale, unit))
+		} else if row == 0 {
+			line = fmt.Sprintf("%-*s", leftSideBarWidth, unit+"/h")
+		}
+
+		for i, h := range hours {
+			fill := eighths[i] - row*8
+			if fill > 8 {
+				fill = 8
+			} else if fill < 0 {
+				fill = 0
+			}
+			bar := strings.Repeat(string(eighthBlocks[fill]), hourWidth-1)
+			if t.color && fill > 0 {
+				bar = ascii.Colorize(bar, precipColor(h.precipType, h.precipProbability))
+			}
+			line += " " + bar
+		}
+		fmt.Println(line)
+	}
+}
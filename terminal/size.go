@@ -0,0 +1,42 @@
+package terminal
+
+import (
+	"os"
+	"strconv"
+)
+
+// size used if the output is not a terminal
+const (
+	DefaultCols = 80
+	DefaultRows = 24
+)
+
+// Options control the output of a Terminal.
+type Options struct {
+	Cols  int  // width in characters, see Size
+	Rows  int  // height in lines, see Size
+	Color bool // use colors and ansi formatting
+}
+
+// DetectOptions returns the options to render to f. The size is taken from
+// COLUMNS and LINES if set, then from the terminal. If f is not a terminal
+// DefaultCols x DefaultRows is used and colors are disabled, as they are
+// when NO_COLOR is set.
+func DetectOptions(f *os.File) Options {
+	opts := Options{Cols: DefaultCols, Rows: DefaultRows}
+	tty := IsTerminal(f)
+	if tty {
+		if cols, rows, ok := terminalSize(f); ok {
+			opts.Cols, opts.Rows = cols, rows
+		}
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		opts.Cols = n
+	}
+	if n, err := strconv.Atoi(os.Getenv("LINES")); err == nil && n > 0 {
+		opts.Rows = n
+	}
+	_, noColor := os.LookupEnv("NO_COLOR")
+	opts.Color = tty && !noColor
+	return opts
+}
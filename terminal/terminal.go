@@ -4,8 +4,6 @@ import (
 	"errors"
 	"fmt"
 	"math"
-	"os"
-	"os/exec"
 	"strconv"
 	"strings"
 	"time"
@@ -21,8 +19,16 @@ const (
 	terminalMinRows  = 24
 	leftSideBarWidth = 6
 	hourWidth        = 4
+	// maxRowsPerTick keeps small temperature changes from being blown up
+	maxRowsPerTick = 4
+	// titleRows are the lines printed around the panels: the location and the prompt
+	titleRows = 2
 )
 
+// tickSteps are the degrees between two labels of the temperature axis,
+// larger steps are multiples of these by ten.
+var tickSteps = []float64{1, 2, 5}
+
 type dayData struct {
 	hourly []hourData
 	tm     *time.Time
@@ -36,45 +42,42 @@ type hourData struct {
 	precipProbability float64
 	precipType        string
 	cloudCover        float64
+	icon              string
+	windSpeed         float64
+	windGust          float64
+	windBearing       float64
 }
 
 // Terminal represents the basic type to render ascii weather
 type Terminal struct {
-	rows      int
-	cols      int
-	hours     int
-	maxTemp   float64
-	minTemp   float64
-	tempRange int
-	tempUnit  string
-	days      []dayData
-	forecast  *forecastio.Forecast
-	conf      *config.Config
+	rows     int
+	cols     int
+	hours    int
+	maxTemp  float64
+	minTemp  float64
+	tempUnit string
+	// temperature axis from axisMin in steps of axisStep degrees
+	// with rowsPerTick canvas rows between two ticks
+	axisMin     float64
+	axisStep    float64
+	axisTicks   int
+	rowsPerTick int
+	days        []dayData
+	forecast    *forecastio.Forecast
+	settings    config.LocationSettings
+	color       bool
 	// canvas represents the weather curve area
 	canvas *ascii.Canvas
 }
 
-// NewTerminal creates a new terminal renderer with forecast data
-func NewTerminal(fc *forecastio.Forecast) (*Terminal, error) {
-	t := &Terminal{}
+// NewTerminal creates a new terminal renderer with forecast data,
+// the settings of the forecast location and the output options
+func NewTerminal(fc *forecastio.Forecast, settings config.LocationSettings, opts Options) (*Terminal, error) {
+	t := &Terminal{settings: settings, rows: opts.Rows, cols: opts.Cols, color: opts.Color}
 	// check terminal size
-	cmd := exec.Command("stty", "size")
-	cmd.Stdin = os.Stdin
-	resp, err := cmd.Output()
-	if err != nil {
-		return nil, errors.New("Cannot detect terminal: " + err.Error())
-	}
-
-	size := strings.Fields(string(resp))
-	if t.rows, err = strconv.Atoi(size[0]); err != nil {
-		return nil, errors.New("Cannot read terminal size: " + err.Error())
-	}
 	if t.rows < terminalMinRows {
 		return nil, errors.New("Terminal is to small: Number of rows must be at least " + strconv.Itoa(terminalMinRows))
 	}
-	if t.cols, err = strconv.Atoi(size[1]); err != nil {
-		return nil, errors.New("Cannot read terminal size: " + err.Error())
-	}
 	if t.cols < terminalMinCols {
 		return nil, errors.New("Terminal is to small: Number of columns must be at least " + strconv.Itoa(terminalMinCols))
 	}
@@ -113,7 +116,7 @@ func (t *Terminal) init() {
 	// get all data points for the presentable time interval
 	for i := 0; i < t.hours; i++ {
 		hData := t.forecast.Hourly.Data[i]
-		tim := time.Unix(hData.Time, 0).Local()
+		tim := time.Unix(hData.Time, 0).In(t.settings.Timezone)
 
 		if tim.Hour() == 0 && len(day.hourly) > 0 {
 			// the last day is over and is not an empty dummy object..
@@ -139,6 +142,10 @@ func (t *Terminal) init() {
 			precipProbability: hData.PrecipProbability,
 			precipType:        hData.PrecipType,
 			cloudCover:        hData.CloudCover,
+			icon:              hData.Code,
+			windSpeed:         hData.WindSpeed,
+			windGust:          hData.WindGust,
+			windBearing:       hData.WindBearing,
 		})
 	}
 
@@ -147,20 +154,11 @@ func (t *Terminal) init() {
 		t.days = append(t.days, day)
 	}
 
-	// calculate range for temperature scale
-	t.maxTemp = math.Round(t.maxTemp)
-	//	if int(t.maxTemp)%2 != 0 {
-	//		t.maxTemp++
-	//	}
-
-	t.minTemp = math.Round(t.minTemp)
-	//	if int(t.minTemp)%2 != 0 {
-	//		t.minTemp--
-	//	}
+	// fit the temperature scale to the rows left by the other panels
+	t.scaleAxis(t.temperatureRows())
 
-	t.tempRange = int(t.maxTemp - t.minTemp + 1) // bounds inclusive
-
-	t.canvas = ascii.NewCanvas(t.tempRange, t.hours*hourWidth)
+	t.canvas = ascii.NewCanvas(t.axisTicks*t.rowsPerTick+1, t.hours*hourWidth)
+	t.canvas.NoColor = !t.color
 }
 
 // Render renders the weather forecast to the terminal
@@ -204,8 +202,8 @@ func (t *Terminal) Render() {
 
 		// build canvas with hours
 		for _, hour := range day.hourly {
-			scaleTemp := int(math.Round(hour.temp - t.minTemp))
-			color := utils.NewColorByTemp(hour.temp, config.Settings.HeatMap, t.tempUnit)
+			scaleTemp := t.tempRow(hour.temp)
+			color := utils.NewColorByTemp(hour.temp, t.settings.HeatMap, t.tempUnit)
 
 			column := hourCount*hourWidth + hourWidth/2
 
@@ -223,26 +221,6 @@ func (t *Terminal) Render() {
 				t.canvas.Set(scaleTemp, column, '\u2501') //\u2501 \u254B
 			}
 
-			// set weather indicators -> sunny, rainy, snowy, cloudy...
-			// TODO -- put this in a function
-			//			t.canvas.SetAnsi(0, column, ascii.Bold)
-			//			if hour.precipProbability > 0.3 { // rainy / snowy
-			//				if hour.precipType == "rain" {
-			//					t.canvas.SetColor(0, column, utils.Color{R: 0, G: 5, B: 5})
-			//					t.canvas.Set(0, column, '\u2614')
-			//				} else { // freezy
-			//					// snow, hail, ..
-			//					t.canvas.SetColor(0, column, utils.Color{R: 5, G: 5, B: 5})
-			//					t.canvas.Set(0, column, '*')
-			//				}
-			//			} else if hour.cloudCover >= 0.4 { // cloudy
-			//				t.canvas.SetColor(0, column, utils.Color{R: 4, G: 4, B: 4})
-			//				t.canvas.Set(0, column, '\u2601')
-			//			} else { // sunny
-			//				t.canvas.SetColor(0, column, utils.Color{R: 5, G: 5, B: 0})
-			//				t.canvas.Set(0, column, '\u2600')
-			//			}
-
 			if hour.tm.Hour() == 0 || hourCount == 0 {
 				// set vertical ..
 				t.canvas.SetVerticalBar(hourCount*hourWidth, '\u2502')
@@ -254,22 +232,104 @@ func (t *Terminal) Render() {
 		}
 	}
 
-	fmt.Println(headerTop)
-	fmt.Println(headerMiddle)
-	fmt.Println(headerBottom)
-
-	for i := int(t.maxTemp); i >= int(t.minTemp); i-- {
-		fmt.Printf("%3d°%s %s\n", i, t.tempUnit, t.canvas.Row(i-int(t.minTemp)))
-		//		if i%2 == 0 {
-		//			fmt.Printf("%3d°%s %s\n", i, t.tempUnit, t.canvas.Row(i-int(t.minTemp)))
-		//		} else {
-		//			fmt.Printf("%s%s\n", strings.Repeat(" ", leftSideBarWidth), t.canvas.Row(i-int(t.minTemp)))
-		//		}
+	for _, panel := range t.settings.Panels {
+		switch panel {
+		case config.PanelDays:
+			fmt.Println(headerTop)
+			fmt.Println(headerMiddle)
+			fmt.Println(headerBottom)
+		case config.PanelWeather:
+			t.renderWeather()
+		case config.PanelWind:
+			t.renderWind()
+		case config.PanelTemperature:
+			t.renderTemperature()
+		case config.PanelHours:
+			t.renderHours(hours)
+		case config.PanelPrecip:
+			t.renderPrecipitation()
+		}
 	}
+}
+
+// renderTemperature prints the temperature curve with its scale.
+// Only rows at tick marks are labeled.
+func (t *Terminal) renderTemperature() {
+	for row := t.axisTicks * t.rowsPerTick; row >= 0; row-- {
+		if row%t.rowsPerTick == 0 {
+			label := t.axisMin + float64(row/t.rowsPerTick)*t.axisStep
+			fmt.Printf("%3d°%s %s\n", int(label), t.tempUnit, t.canvas.Row(row))
+		} else {
+			fmt.Printf("%s%s\n", strings.Repeat(" ", leftSideBarWidth), t.canvas.Row(row))
+		}
+	}
+}
+
+// panelRows returns the number of lines a panel takes.
+// The temperature panel gets all rows the others leave.
+func panelRows(panel string) int {
+	switch panel {
+	case config.PanelDays:
+		return 3
+	case config.PanelWeather, config.PanelWind:
+		return 1
+	case config.PanelHours:
+		return 2
+	case config.PanelPrecip:
+		return precipRows
+	}
+	return 0
+}
+
+// temperatureRows returns the rows available to the temperature panel.
+func (t *Terminal) temperatureRows() int {
+	rows := t.rows - titleRows
+	for _, panel := range t.settings.Panels {
+		rows -= panelRows(panel)
+	}
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// scaleAxis chooses the smallest tick step of 1, 2, 5, 10, 20.. degrees
+// for which the temperature range fits into rows and spreads the ticks
+// over the rows.
+func (t *Terminal) scaleAxis(rows int) {
+	for magnitude := 1.0; ; magnitude *= 10 {
+		for _, s := range tickSteps {
+			step := s * magnitude
+			lo := math.Floor(t.minTemp/step) * step
+			hi := math.Ceil(t.maxTemp/step) * step
+			if hi <= lo {
+				hi = lo + step
+			}
+			ticks := int(math.Round((hi - lo) / step))
+			if ticks+1 <= rows {
+				t.axisMin, t.axisStep, t.axisTicks = lo, step, ticks
+				t.rowsPerTick = (rows - 1) / ticks
+				if t.rowsPerTick > maxRowsPerTick {
+					t.rowsPerTick = maxRowsPerTick
+				}
+				return
+			}
+		}
+	}
+}
+
+// tempRow returns the canvas row of temperature temp. The position is
+// computed on the fractional scale and rounded to the nearest row.
+func (t *Terminal) tempRow(temp float64) int {
+	row := (temp - t.axisMin) / t.axisStep * float64(t.rowsPerTick)
+	return int(math.Round(row))
+}
 
+// renderHours prints the hour axis.
+func (t *Terminal) renderHours(hours []int) {
 	outerScale := strings.Repeat(" ", leftSideBarWidth) +
 		fmt.Sprintf("\u2514%s%s\u2534%s\u2518", strings.Repeat("\u2500", hourWidth-1), "%s", strings.Repeat("\u2500", hourWidth-1))
-	innerScale := strings.Repeat(fmt.Sprintf("\u2534%s", strings.Repeat("\u2500", hourWidth-1)), hourCount-2)
+	innerScale := strings.Repeat(fmt.Sprintf("\u2534%s", strings.Repeat("\u2500", hourWidth-1)), len(hours)-2)
 	hourScale := fmt.Sprintf(outerScale, innerScale)
 	fmt.Println(hourScale)
 	fmt.Printf(strings.Repeat(" ", leftSideBarWidth))
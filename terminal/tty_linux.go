@@ -0,0 +1,30 @@
+package terminal
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// IsTerminal reports whether f is connected to a terminal.
+func IsTerminal(f *os.File) bool {
+	var termios syscall.Termios
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TCGETS, uintptr(unsafe.Pointer(&termios)))
+	return errno == 0
+}
+
+// winsize is struct winsize of <sys/ioctl.h>.
+type winsize struct {
+	rows, cols     uint16
+	xpixel, ypixel uint16
+}
+
+// terminalSize asks the terminal f for its size.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	var ws winsize
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, f.Fd(), syscall.TIOCGWINSZ, uintptr(unsafe.Pointer(&ws)))
+	if errno != 0 || ws.cols == 0 || ws.rows == 0 {
+		return 0, 0, false
+	}
+	return int(ws.cols), int(ws.rows), true
+}
@@ -0,0 +1,23 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package terminal
+
+import (
+	"os"
+)
+
+// IsTerminal reports whether f is connected to a terminal. Without a
+// termios ioctl this is approximated by checking for a character device.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// terminalSize is not supported on this platform.
+func terminalSize(f *os.File) (cols, rows int, ok bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,248 @@
+package terminal
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/dbriemann/sunlens/ascii"
+	"github.com/dbriemann/sunlens/config"
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// weather conditions shown by the weather panel, named like the icon codes of the api
+const (
+	clearDay          = "clear-day"
+	clearNight        = "clear-night"
+	partlyCloudyDay   = "partly-cloudy-day"
+	partlyCloudyNight = "partly-cloudy-night"
+	cloudy            = "cloudy"
+	rain              = "rain"
+	snow              = "snow"
+	sleet             = "sleet"
+	hail              = "hail"
+	wind              = "wind"
+	fog               = "fog"
+	thunderstorm      = "thunderstorm"
+	tornado           = "tornado"
+)
+
+// precipThreshold is the probability from which precipitation is shown instead of clouds.
+const precipThreshold = 0.3
+
+// glyph is the symbol of a condition and the number of columns it takes.
+type glyph struct {
+	text  string
+	width int
+}
+
+// glyphSets map conditions to symbols. Emoji take two columns in most terminals.
+var glyphSets = map[string]map[string]glyph{
+	config.GlyphsUnicode: {
+		clearDay:          {"☀", 1},
+		clearNight:        {"☾", 1},
+		partlyCloudyDay:   {"☁", 1},
+		partlyCloudyNight: {"☁", 1},
+		cloudy:            {"☁", 1},
+		rain:              {"☂", 1},
+		snow:              {"❄", 1},
+		sleet:             {"❅", 1},
+		hail:              {"⁂", 1},
+		wind:              {"≋", 1},
+		fog:               {"≡", 1},
+		thunderstorm:      {"ϟ", 1},
+		tornado:           {"@", 1},
+	},
+	config.GlyphsEmoji: {
+		clearDay:          {"☀️", 2},
+		clearNight:        {"🌙", 2},
+		partlyCloudyDay:   {"⛅", 2},
+		partlyCloudyNight: {"☁️", 2},
+		cloudy:            {"☁️", 2},
+		rain:              {"🌧️", 2},
+		snow:              {"❄️", 2},
+		sleet:             {"🌨️", 2},
+		hail:              {"🧊", 2},
+		wind:              {"💨", 2},
+		fog:               {"🌫️", 2},
+		thunderstorm:      {"⛈️", 2},
+		tornado:           {"🌪️", 2},
+	},
+	config.GlyphsASCII: {
+		clearDay:          {"O", 1},
+		clearNight:        {")", 1},
+		partlyCloudyDay:   {"o", 1},
+		partlyCloudyNight: {"(", 1},
+		cloudy:            {"~", 1},
+		rain:              {"/", 1},
+		snow:              {"*", 1},
+		sleet:             {"%", 1},
+		hail:              {"#", 1},
+		wind:              {">", 1},
+		fog:               {"=", 1},
+		thunderstorm:      {"!", 1},
+		tornado:           {"@", 1},
+	},
+}
+
+// conditionColors are the colors of the symbols.
+var conditionColors = map[string]utils.Color{
+	clearDay:          {R: 5, G: 5, B: 0},
+	clearNight:        {R: 4, G: 4, B: 5},
+	partlyCloudyDay:   {R: 5, G: 5, B: 3},
+	partlyCloudyNight: {R: 3, G: 3, B: 4},
+	cloudy:            {R: 4, G: 4, B: 4},
+	rain:              {R: 0, G: 5, B: 5},
+	snow:              {R: 5, G: 5, B: 5},
+	sleet:             {R: 3, G: 4, B: 5},
+	hail:              {R: 4, G: 5, B: 5},
+	wind:              {R: 3, G: 5, B: 3},
+	fog:               {R: 3, G: 3, B: 3},
+	thunderstorm:      {R: 5, G: 4, B: 0},
+	tornado:           {R: 5, G: 1, B: 1},
+}
+
+// glyphSet returns the symbols for the configured set. The automatic
+// choice falls back to ascii if the locale is not UTF-8.
+func glyphSet(name string) map[string]glyph {
+	if set, ok := glyphSets[name]; ok {
+		return set
+	}
+	if useASCII(name) {
+		return glyphSets[config.GlyphsASCII]
+	}
+	return glyphSets[config.GlyphsUnicode]
+}
+
+// useASCII reports whether the glyph set name means plain ascii symbols.
+func useASCII(name string) bool {
+	if _, ok := glyphSets[name]; ok {
+		return name == config.GlyphsASCII
+	}
+	return !utf8Locale()
+}
+
+// utf8Locale reports whether the locale of the environment uses UTF-8.
+func utf8Locale() bool {
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+	return false
+}
+
+// condition returns the weather condition of an hour. Likely precipitation
+// wins over the icon code and day or night follow sunrise and sunset.
+func (t *Terminal) condition(h hourData) string {
+	if h.icon == thunderstorm || h.icon == tornado {
+		return h.icon
+	}
+	if h.precipProbability >= precipThreshold {
+		switch h.precipType {
+		case snow, sleet, hail:
+			return h.precipType
+		}
+		return rain
+	}
+
+	day := t.isDay(h)
+	switch h.icon {
+	case clearDay, clearNight:
+		if day {
+			return clearDay
+		}
+		return clearNight
+	case partlyCloudyDay, partlyCloudyNight:
+		if day {
+			return partlyCloudyDay
+		}
+		return partlyCloudyNight
+	case cloudy, wind, fog:
+		return h.icon
+	}
+
+	// unknown codes and precipitation codes without a likely precipitation
+	switch {
+	case h.cloudCover >= 0.75:
+		return cloudy
+	case h.cloudCover >= 0.4 && day:
+		return partlyCloudyDay
+	case h.cloudCover >= 0.4:
+		return partlyCloudyNight
+	case day:
+		return clearDay
+	}
+	return clearNight
+}
+
+// isDay reports whether the sun is up in the middle of the hour h.
+func (t *Terminal) isDay(h hourData) bool {
+	// sunrise and sunset are unix times, so no timezone is involved
+	mid := h.tm.Add(30 * time.Minute).Unix()
+	const day = 24 * 60 * 60
+	setBefore, riseAfter := false, false
+	for _, d := range t.forecast.Daily.Data {
+		if d.SunriseTime == 0 && d.SunsetTime == 0 {
+			continue
+		}
+		if mid >= d.SunriseTime && mid < d.SunsetTime {
+			return true
+		}
+		if d.SunsetTime != 0 && d.SunsetTime <= mid && mid-d.SunsetTime < day {
+			setBefore = true
+		}
+		if d.SunriseTime != 0 && d.SunriseTime > mid && d.SunriseTime-mid < day {
+			riseAfter = true
+		}
+	}
+	// between a sunset and the next sunrise
+	if setBefore && riseAfter {
+		return false
+	}
+	// polar days and nights or no daily data
+	if strings.HasSuffix(h.icon, "-night") {
+		return false
+	}
+	if strings.HasSuffix(h.icon, "-day") {
+		return true
+	}
+	return h.tm.Hour() >= 6 && h.tm.Hour() < 18
+}
+
+// renderWeather prints a row with the weather symbol of every hour.
+func (t *Terminal) renderWeather() {
+	set := glyphSet(t.settings.Glyphs)
+	row := strings.Repeat(" ", leftSideBarWidth)
+	hourCount := 0
+	for _, day := range t.days {
+		for _, hour := range day.hourly {
+			cell := " "
+			if hour.tm.Hour() == 0 || hourCount == 0 {
+				cell = "│"
+			}
+
+			cond := t.condition(hour)
+			g, ok := set[cond]
+			if !ok {
+				g = glyphSets[config.GlyphsASCII][cond]
+			}
+			// the symbol is centered like the points of the temperature curve
+			pad := hourWidth/2 - 1
+			if g.width == 2 {
+				pad--
+			}
+			text := g.text
+			if t.color {
+				text = ascii.Colorize(text, conditionColors[cond])
+			}
+			cell += strings.Repeat(" ", pad) + text + strings.Repeat(" ", hourWidth-1-pad-g.width)
+
+			row += cell
+			hourCount++
+		}
+	}
+	fmt.Println(row)
+}
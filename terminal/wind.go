@@ -0,0 +1,88 @@
+package terminal
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/dbriemann/sunlens/ascii"
+	"github.com/dbriemann/sunlens/utils"
+)
+
+// beaufortLimits are the lower wind speeds in m/s of Beaufort force 1 to 12.
+var beaufortLimits = []float64{0.5, 1.6, 3.4, 5.5, 8.0, 10.8, 13.9, 17.2, 20.8, 24.5, 28.5, 32.7}
+
+// beaufortColors are the colors of Beaufort force 0 to 12, from calm
+// over fresh breeze (5) and gale (8) to hurricane (12).
+var beaufortColors = []utils.Color{
+	{R: 3, G: 3, B: 3}, {R: 3, G: 4, B: 4}, {R: 2, G: 4, B: 4}, {R: 1, G: 5, B: 3},
+	{R: 2, G: 5, B: 1}, {R: 4, G: 5, B: 0}, {R: 5, G: 5, B: 0}, {R: 5, G: 3, B: 0},
+	{R: 5, G: 2, B: 0}, {R: 5, G: 0, B: 0}, {R: 4, G: 0, B: 2}, {R: 5, G: 0, B: 4},
+	{R: 5, G: 0, B: 5},
+}
+
+// gustSpread is the difference in m/s (10 knots) from which gusts are marked.
+const gustSpread = 5.14
+
+// arrows point where the wind blows to, starting north and going clockwise.
+var (
+	arrows      = []string{"↑", "↗", "→", "↘", "↓", "↙", "←", "↖"}
+	asciiArrows = []string{"^", "/", ">", "\\", "v", "/", "<", "\\"}
+)
+
+// beaufort returns the Beaufort force of a wind speed in m/s.
+func beaufort(speed float64) int {
+	force := 0
+	for force < len(beaufortLimits) && speed >= beaufortLimits[force] {
+		force++
+	}
+	return force
+}
+
+// windUnit returns the wind speed unit of the forecast and the factor to m/s.
+func (t *Terminal) windUnit() (string, float64) {
+	switch t.forecast.Flags.Units {
+	case "ca":
+		return "km/h", 1 / 3.6
+	case "uk", "uk2", "us":
+		return "mph", 0.44704
+	}
+	return "m/s", 1
+}
+
+// renderWind prints a row with an arrow for the wind direction and the
+// speed of every hour. A "!" marks gusts at least 10 knots above the speed.
+func (t *Terminal) renderWind() {
+	unit, toMS := t.windUnit()
+	dirs, calm := arrows, "·"
+	if useASCII(t.settings.Glyphs) {
+		dirs, calm = asciiArrows, "."
+	}
+
+	line := fmt.Sprintf("%-*s", leftSideBarWidth, unit)
+	for _, day := range t.days {
+		for _, h := range day.hourly {
+			speed := math.Round(h.windSpeed)
+			force := beaufort(h.windSpeed * toMS)
+
+			// the bearing tells where the wind comes from
+			arrow := calm
+			if force > 0 {
+				arrow = dirs[int(math.Mod(h.windBearing+180+22.5, 360)/45)%8]
+			}
+			cell := arrow + fmt.Sprintf("%2d", int(math.Min(speed, 99)))
+			if t.color {
+				cell = ascii.Colorize(cell, beaufortColors[force])
+			}
+
+			gust := " "
+			if h.windGust > 0 && (h.windGust-h.windSpeed)*toMS >= gustSpread {
+				gust = "!"
+				if t.color {
+					gust = ascii.Colorize(gust, beaufortColors[beaufort(h.windGust*toMS)])
+				}
+			}
+			line += cell + gust
+		}
+	}
+	fmt.Println(line)
+}
@@ -1,5 +1,7 @@
 package utils
 
+import "math"
+
 //Color defines a color in RGB with values from 0 to 5 each. -> 216 colors
 type Color struct {
 	R uint8
@@ -61,3 +63,64 @@ func ColorByInterpolation(hcLo, hcHi *HeatColor, temp float64) Color {
 
 	return c
 }
+
+// EarthRadius is the mean earth radius in kilometers.
+const EarthRadius = 6371.0
+
+// Distance returns the great circle distance in kilometers between two coordinates.
+func Distance(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180.0
+	dLat := (lat2 - lat1) * rad
+	dLng := (lng2 - lng1) * rad
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+
+	return 2 * EarthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Levenshtein returns the edit distance between the strings a and b.
+func Levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Bearing returns the initial compass bearing in degrees (0 to 360)
+// for travelling from the first coordinate to the second one.
+func Bearing(lat1, lng1, lat2, lng2 float64) float64 {
+	rad := math.Pi / 180.0
+	dLng := (lng2 - lng1) * rad
+	y := math.Sin(dLng) * math.Cos(lat2*rad)
+	x := math.Cos(lat1*rad)*math.Sin(lat2*rad) - math.Sin(lat1*rad)*math.Cos(lat2*rad)*math.Cos(dLng)
+
+	return math.Mod(math.Atan2(y, x)/rad+360, 360)
+}
+
+// CompassPoint returns the 8-wind compass direction (N, NE, E ..) for bearing.
+func CompassPoint(bearing float64) string {
+	points := []string{"N", "NE", "E", "SE", "S", "SW", "W", "NW"}
+	return points[int(math.Mod(bearing+22.5, 360)/45)%8]
+}